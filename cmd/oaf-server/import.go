@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/graph/resolver"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+)
+
+const importUsage = "usage: oaf-server import [-dry-run] [-section id]... <username> <organization id> <file.ics>"
+
+// importEvents imports the events of an iCalendar file into an
+// organization on behalf of a user, as the importEvents mutation does,
+// and prints what was imported.
+func importEvents(ctx context.Context, cfg config.Database, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without creating events")
+	var sections idList
+	flags.Var(&sections, "section", "ID of a section the events are for, repeatable")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 3 {
+		return fmt.Errorf(importUsage)
+	}
+	username, organization, path := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	organizationID, err := relay.Decode(organization, "Organization")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	store, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	u, err := store.Users.GetByUsername(ctx, username)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", username, err)
+	}
+	r := &resolver.Resolver{Store: store}
+	result, err := r.ImportCalendar(ctx, u.ID, organizationID, sections, f, *dryRun)
+	if err != nil {
+		return err
+	}
+	printImport(result)
+	return nil
+}
+
+func printImport(result *model.ImportResult) {
+	for _, ev := range result.Events {
+		name, start := "(no summary)", "(no start)"
+		if ev.Name != nil {
+			name = *ev.Name
+		}
+		if ev.Start != nil {
+			start = ev.Start.Format(time.RFC3339)
+		}
+		fmt.Printf("%-9s %s %s\n", ev.Status, start, name)
+		if ev.Message != nil {
+			fmt.Printf("          %s\n", *ev.Message)
+		}
+		if ev.DuplicateOf != nil {
+			fmt.Printf("          duplicate of %s\n", relay.ToGlobalID("Event", ev.DuplicateOf.Key()))
+		}
+		for _, e := range ev.Conflicts {
+			fmt.Printf("          conflicts with %s %s %s\n", relay.ToGlobalID("Event", e.Key()), e.Start.Format(time.RFC3339), e.Name)
+		}
+		for _, i := range ev.CalendarConflicts {
+			other := result.Events[i]
+			fmt.Printf("          conflicts with %s %s of the calendar\n", other.Start.Format(time.RFC3339), *other.Name)
+		}
+	}
+	if result.DryRun {
+		fmt.Printf("%d of %d events would be imported\n", result.Created, len(result.Events))
+	} else {
+		fmt.Printf("%d of %d events imported\n", result.Created, len(result.Events))
+	}
+}
+
+// idList collects the values of a repeated flag.
+type idList []string
+
+func (l *idList) String() string { return strings.Join(*l, ",") }
+
+func (l *idList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,136 @@
+// Command oaf-server runs the Open Attendee Framework GraphQL API.
+//
+// Usage:
+//
+//	oaf-server [-config file] [serve]
+//	oaf-server [-config file] migrate up|down|status
+//	oaf-server [-config file] superuser grant|revoke <username>
+//	oaf-server [-config file] import [-dry-run] [-section id]... <username> <organization id> <file.ics>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	// Organizations name their time zone, which must resolve on hosts
+	// without a zoneinfo database too.
+	_ "time/tzdata"
+
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/config"
+	"github.com/concertLabs/oaf-server/pkg/graph/generated"
+	"github.com/concertLabs/oaf-server/pkg/graph/loader"
+	"github.com/concertLabs/oaf-server/pkg/graph/resolver"
+	"github.com/concertLabs/oaf-server/pkg/mail"
+	"github.com/concertLabs/oaf-server/pkg/pubsub"
+	"github.com/concertLabs/oaf-server/pkg/server"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+	"github.com/concertLabs/oaf-server/pkg/storage/sqlstore"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the YAML configuration file")
+	flag.Parse()
+
+	if err := run(*configPath, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(configPath string, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(args) == 0 {
+		return serve(ctx, cfg)
+	}
+	switch args[0] {
+	case "serve":
+		return serve(ctx, cfg)
+	case "migrate":
+		return migrate(ctx, cfg.Database, args[1:])
+	case "superuser":
+		return superuser(ctx, cfg.Database, args[1:])
+	case "import":
+		return importEvents(ctx, cfg.Database, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func serve(ctx context.Context, cfg config.Config) error {
+	store, closeStore, err := openStore(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	authService := auth.NewService([]byte(cfg.Auth.Secret), cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL, store)
+
+	r := &resolver.Resolver{
+		Store:            store,
+		Auth:             authService,
+		Bus:              pubsub.New(),
+		Mailer:           mail.New(cfg.Mail),
+		InviteTTL:        cfg.Invites.TTL,
+		CalendarReminder: cfg.Calendar.Reminder,
+	}
+	es := generated.NewExecutableSchema(generated.Config{
+		Resolvers:  r,
+		Directives: r.Directives(),
+		Complexity: resolver.Complexity(cfg.Server.Limits.ListSize),
+	})
+	loaders := loader.Extension{Store: store}
+	srv, err := server.New(cfg.Server, es, authService, resolver.CalendarPath, r.CalendarHandler(), loaders, r.Validation())
+	if err != nil {
+		return err
+	}
+	return srv.Run(ctx)
+}
+
+// openStore opens the configured storage backend and makes sure its
+// schema is current. The returned function releases the backend.
+func openStore(ctx context.Context, cfg config.Database) (*storage.Store, func() error, error) {
+	if cfg.Driver == config.DriverMemory {
+		log.Printf("using in-memory storage, data is lost on exit")
+		return memory.New(), func() error { return nil }, nil
+	}
+
+	db, err := sqlstore.Open(ctx, cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := db.MigrateUp(ctx)
+		for _, m := range applied {
+			log.Printf("applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	} else {
+		pending, err := db.Pending(ctx)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		if len(pending) > 0 {
+			db.Close()
+			return nil, nil, fmt.Errorf("database has %d pending migrations, run `oaf-server migrate up` or enable database.autoMigrate", len(pending))
+		}
+	}
+	return db.Store(), db.Close, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+	"github.com/concertLabs/oaf-server/pkg/storage/sqlstore"
+)
+
+func migrate(ctx context.Context, cfg config.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: oaf-server migrate up|down|status")
+	}
+	if cfg.Driver == config.DriverMemory {
+		return fmt.Errorf("the %s driver has no schema to migrate", cfg.Driver)
+	}
+
+	db, err := sqlstore.Open(ctx, cfg.Driver, cfg.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		m, err := db.MigrateDown(ctx)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			fmt.Println("no migration to revert")
+		} else {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+		return nil
+	case "status":
+		status, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+// superuser grants or revokes the superuser flag, which cannot be changed
+// through the API. It is how the first administrator is created.
+func superuser(ctx context.Context, cfg config.Database, args []string) error {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		return fmt.Errorf("usage: oaf-server superuser grant|revoke <username>")
+	}
+
+	store, closeStore, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	u, err := store.Users.GetByUsername(ctx, args[1])
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[1], err)
+	}
+	u.Superuser = args[0] == "grant"
+	if err := store.Users.Update(ctx, u); err != nil {
+		return err
+	}
+	fmt.Printf("%s superuser: %t\n", u.Username, u.Superuser)
+	return nil
+}
@@ -5,7 +5,11 @@ go 1.16
 require (
 	github.com/99designs/gqlgen v0.14.0
 	github.com/agnivade/levenshtein v1.1.1 // indirect
+	github.com/golang-jwt/jwt/v4 v4.4.2
+	github.com/google/uuid v1.3.0
+	github.com/lib/pq v1.10.2
 	github.com/vektah/gqlparser/v2 v2.2.0
-	gopkg.in/yaml.v2 v2.4.0 // indirect
-
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.14.8
 )
@@ -0,0 +1,96 @@
+// Package apperr defines the errors reported to clients. Each carries a
+// code telling clients how to react, which the server puts into the
+// extensions of GraphQL errors. Errors without one are reported as
+// internal errors, hiding their details.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code classifies an error.
+type Code string
+
+const (
+	// NotFound is the code of lookups of entities that do not exist.
+	NotFound Code = "NOT_FOUND"
+	// Forbidden is the code of operations the caller lacks the rights for.
+	Forbidden Code = "FORBIDDEN"
+	// Unauthenticated is the code of operations requiring a user, and of
+	// invalid credentials or tokens.
+	Unauthenticated Code = "UNAUTHENTICATED"
+	// Validation is the code of invalid input.
+	Validation Code = "VALIDATION"
+	// Conflict is the code of writes clashing with the stored state, such
+	// as a taken username.
+	Conflict Code = "CONFLICT"
+	// Internal is the code of unexpected failures.
+	Internal Code = "INTERNAL"
+)
+
+// Error is an error with a code. Wrapping it, as in
+// fmt.Errorf("%w: details", err), keeps its code.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields details which input fields a Validation error is about.
+	Fields []FieldError
+	err    error
+}
+
+// FieldError describes what is wrong with an input field.
+type FieldError struct {
+	// Field is the path of the field in the arguments, such as
+	// "input.email".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns an Error with the given code and message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf is like fmt.Errorf but returns an Error with the given code.
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Code: code, Message: err.Error(), err: errors.Unwrap(err)}
+}
+
+// Invalid returns a Validation error about the given fields.
+func Invalid(fields ...FieldError) *Error {
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return &Error{
+		Code:    Validation,
+		Message: "invalid input: " + strings.Join(msgs, "; "),
+		Fields:  fields,
+	}
+}
+
+// Field returns a Validation error about a single field.
+func Field(field, format string, args ...interface{}) *Error {
+	return Invalid(FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// CodeOf returns the code of the first Error in the chain of err, or
+// Internal if there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
@@ -0,0 +1,205 @@
+// Package auth authenticates users and issues the tokens they present to
+// the API.
+//
+// Login hands out a short-lived JWT access token and an opaque refresh
+// token. Refresh tokens are single use: exchanging one yields a new pair
+// and marks the old token used. Presenting a used token again means it has
+// leaked, so every token descending from the same login is revoked.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const issuer = "oaf-server"
+
+var (
+	// ErrInvalidCredentials is returned by Login for an unknown username
+	// or a wrong password.
+	ErrInvalidCredentials = apperr.New(apperr.Unauthenticated, "invalid username or password")
+	// ErrInvalidToken is returned for tokens that are malformed, expired
+	// or revoked.
+	ErrInvalidToken = apperr.New(apperr.Unauthenticated, "invalid or expired token")
+	// ErrWrongPassword is returned by ChangePassword if the old password
+	// does not match.
+	ErrWrongPassword = apperr.New(apperr.Forbidden, "old password is incorrect")
+)
+
+// Tokens is the result of a successful Login or Refresh.
+type Tokens struct {
+	UserID       string
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn is the lifetime of AccessToken.
+	ExpiresIn time.Duration
+}
+
+// Service issues and verifies tokens.
+type Service struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	store      *storage.Store
+	now        func() time.Time
+}
+
+// NewService returns a Service signing access tokens with secret.
+func NewService(secret []byte, accessTTL, refreshTTL time.Duration, store *storage.Store) *Service {
+	return &Service{
+		secret:     secret,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		store:      store,
+		now:        time.Now,
+	}
+}
+
+// Login checks the credentials and starts a new refresh token family.
+func (s *Service) Login(ctx context.Context, username, password string) (*Tokens, error) {
+	u, err := s.store.Users.GetByUsername(ctx, username)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !CheckPassword(u.Password, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issue(ctx, u.ID, uuid.New().String())
+}
+
+// ChangePassword replaces the password of the user with the given ID after
+// checking the old one, and revokes the refresh tokens issued to the user
+// so that sessions started with the old password end when their access
+// token expires.
+func (s *Service) ChangePassword(ctx context.Context, userID, oldPassword, newPassword string) (*model.User, error) {
+	u, err := s.store.Users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !CheckPassword(u.Password, oldPassword) {
+		return nil, ErrWrongPassword
+	}
+	if u.Password, err = HashPassword(newPassword); err != nil {
+		return nil, err
+	}
+	err = s.store.Atomic(ctx, func(tx *storage.Store) error {
+		if err := tx.Users.Update(ctx, u); err != nil {
+			return err
+		}
+		return tx.RefreshTokens.RevokeUser(ctx, u.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	t, err := s.store.RefreshTokens.GetByHash(ctx, HashToken(refreshToken))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Revoked || !s.now().Before(t.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	err = s.store.RefreshTokens.MarkUsed(ctx, t.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Used before, possibly concurrently with this request.
+		log.Printf("refresh token reuse for user %s, revoking token family %s", t.UserID, t.Family)
+		if err := s.store.RefreshTokens.RevokeFamily(ctx, t.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, t.UserID, t.Family)
+}
+
+// ParseAccessToken verifies an access token and returns the ID of the user
+// it was issued to.
+func (s *Service) ParseAccessToken(token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return s.secret, nil
+	})
+	if err != nil || claims.Issuer != issuer || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
+
+func (s *Service) issue(ctx context.Context, userID, family string) (*Tokens, error) {
+	now := s.now()
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+	}).SignedString(s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := RandomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = s.store.RefreshTokens.Create(ctx, &storage.RefreshToken{
+		UserID:    userID,
+		Family:    family,
+		Hash:      HashToken(refresh),
+		ExpiresAt: now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		UserID:       userID,
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    s.accessTTL,
+	}, nil
+}
+
+// RandomToken returns a new unguessable token for use in URLs.
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the form refresh tokens are stored in. Refresh tokens
+// are random, so an unsalted hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
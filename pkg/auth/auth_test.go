@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var testSecret = []byte("test secret")
+
+// newTestService returns a Service over an empty store with the users
+// alice and bob, both with the password "pw", and a clock tests can move.
+func newTestService(t *testing.T) (*Service, *time.Time) {
+	t.Helper()
+	store := memory.New()
+	hash, err := HashPassword("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		u := &model.User{Username: name, Password: hash, Email: name + "@example.org"}
+		if err := store.Users.Create(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewService(testSecret, 15*time.Minute, 24*time.Hour, store)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestLogin(t *testing.T) {
+	s, _ := newTestService(t)
+	tests := []struct {
+		username, password string
+		wantErr            error
+	}{
+		{"alice", "pw", nil},
+		{"alice", "wrong", ErrInvalidCredentials},
+		{"alice", "", ErrInvalidCredentials},
+		{"carol", "pw", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		tokens, err := s.Login(context.Background(), tt.username, tt.password)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Login(%q, %q) = %v, want %v", tt.username, tt.password, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		id, err := s.ParseAccessToken(tokens.AccessToken)
+		if err != nil || id != tokens.UserID {
+			t.Errorf("ParseAccessToken of the access token = %q, %v, want %q", id, err, tokens.UserID)
+		}
+		if tokens.ExpiresIn != 15*time.Minute {
+			t.Errorf("ExpiresIn = %v, want the access token TTL", tokens.ExpiresIn)
+		}
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	first, err := s.Login(ctx, "alice", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh = %v", err)
+	}
+	if second.UserID != first.UserID {
+		t.Errorf("refreshed tokens are for %s, want %s", second.UserID, first.UserID)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh returned the same refresh token")
+	}
+	third, err := s.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh of the rotated token = %v", err)
+	}
+	if _, err := s.ParseAccessToken(third.AccessToken); err != nil {
+		t.Errorf("ParseAccessToken of the rotated access token = %v", err)
+	}
+}
+
+func TestRefreshReuse(t *testing.T) {
+	tests := []struct {
+		name string
+		// reuse returns the token presented again from the tokens of
+		// the family, oldest first.
+		reuse func(family []*Tokens) string
+	}{
+		{"first", func(family []*Tokens) string { return family[0].RefreshToken }},
+		{"previous", func(family []*Tokens) string { return family[len(family)-2].RefreshToken }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			ctx := context.Background()
+			login, err := s.Login(ctx, "alice", "pw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := s.Login(ctx, "alice", "pw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			family := []*Tokens{login}
+			for i := 0; i < 3; i++ {
+				next, err := s.Refresh(ctx, family[len(family)-1].RefreshToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				family = append(family, next)
+			}
+
+			if _, err := s.Refresh(ctx, tt.reuse(family)); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Refresh of a used token = %v, want ErrInvalidToken", err)
+			}
+			latest := family[len(family)-1].RefreshToken
+			if _, err := s.Refresh(ctx, latest); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Refresh of the latest token after reuse = %v, want the family revoked", err)
+			}
+			if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+				t.Errorf("Refresh of another login after reuse = %v, want it kept", err)
+			}
+		})
+	}
+}
+
+func TestRefreshInvalid(t *testing.T) {
+	s, now := newTestService(t)
+	ctx := context.Background()
+	tokens, err := s.Login(ctx, "alice", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"", "unknown", tokens.AccessToken} {
+		if _, err := s.Refresh(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Refresh(%q) = %v, want ErrInvalidToken", token, err)
+		}
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh of an expired token = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestParseAccessToken(t *testing.T) {
+	s, _ := newTestService(t)
+	now := time.Now()
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+	otherIssuer := valid
+	otherIssuer.Issuer = "someone else"
+	noSubject := valid
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"valid", sign(jwt.SigningMethodHS256, testSecret, valid), "user"},
+		{"HS512", sign(jwt.SigningMethodHS512, testSecret, valid), "user"},
+		{"expired", sign(jwt.SigningMethodHS256, testSecret, expired), ""},
+		{"other secret", sign(jwt.SigningMethodHS256, []byte("other"), valid), ""},
+		{"other issuer", sign(jwt.SigningMethodHS256, testSecret, otherIssuer), ""},
+		{"no subject", sign(jwt.SigningMethodHS256, testSecret, noSubject), ""},
+		{"unsigned", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), ""},
+		{"garbage", "not a token", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		id, err := s.ParseAccessToken(tt.token)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%s: ParseAccessToken = %q, %v, want ErrInvalidToken", tt.name, id, err)
+			}
+			continue
+		}
+		if err != nil || id != tt.want {
+			t.Errorf("%s: ParseAccessToken = %q, %v, want %q", tt.name, id, err, tt.want)
+		}
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	alice, err := s.Login(ctx, "alice", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := s.Login(ctx, "bob", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ChangePassword(ctx, alice.UserID, "wrong", "new password"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangePassword with a wrong old password = %v, want ErrWrongPassword", err)
+	}
+	if _, err := s.ChangePassword(ctx, alice.UserID, "pw", "new password"); err != nil {
+		t.Fatalf("ChangePassword = %v", err)
+	}
+
+	if _, err := s.Refresh(ctx, alice.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh of a token issued before the change = %v, want ErrInvalidToken", err)
+	}
+	if _, err := s.Refresh(ctx, bob.RefreshToken); err != nil {
+		t.Errorf("Refresh of another user's token = %v, want it kept", err)
+	}
+	if _, err := s.Login(ctx, "alice", "pw"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login with the old password = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.Login(ctx, "alice", "new password"); err != nil {
+		t.Errorf("Login with the new password = %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	s, _ := newTestService(t)
+	tokens, err := s.Login(context.Background(), "alice", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header string
+		status int
+		user   string
+	}{
+		{"anonymous", "", http.StatusOK, ""},
+		{"bearer", "Bearer " + tokens.AccessToken, http.StatusOK, "alice"},
+		{"other scheme", "Basic " + tokens.AccessToken, http.StatusUnauthorized, ""},
+		{"no scheme", tokens.AccessToken, http.StatusUnauthorized, ""},
+		{"invalid token", "Bearer invalid", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		var user string
+		h := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if u, ok := UserFromContext(r.Context()); ok {
+				user = u.Username
+			}
+		}))
+		req := httptest.NewRequest("POST", "/query", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status || user != tt.user {
+			t.Errorf("%s: status %d for user %q, want %d for %q", tt.name, rec.Code, user, tt.status, tt.user)
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without a WWW-Authenticate header", tt.name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+// ErrUnauthenticated is returned by RequireUser for anonymous requests.
+var ErrUnauthenticated = apperr.New(apperr.Unauthenticated, "authentication required")
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying u as the calling user.
+func WithUser(ctx context.Context, u *model.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// UserFromContext returns the calling user, if the request was
+// authenticated.
+func UserFromContext(ctx context.Context) (*model.User, bool) {
+	u, ok := ctx.Value(contextKey{}).(*model.User)
+	return u, ok
+}
+
+// RequireUser is like UserFromContext but fails for anonymous requests.
+func RequireUser(ctx context.Context) (*model.User, error) {
+	u, ok := UserFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	return u, nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// Middleware authenticates requests carrying an access token in the
+// Authorization header and stores the user in the request context.
+// Requests without the header pass through anonymously; requests with an
+// invalid token are rejected so that clients know to refresh it.
+func (s *Service) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			unauthorized(w, "authorization header must use the Bearer scheme")
+			return
+		}
+		u, err := s.Authenticate(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			unauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("authenticating request: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), u)))
+	})
+}
+
+// Authenticate verifies an access token and loads the user it belongs to.
+func (s *Service) Authenticate(ctx context.Context, token string) (*model.User, error) {
+	id, err := s.ParseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.store.Users.Get(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	return u, err
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="oaf-server"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": msg}},
+	})
+}
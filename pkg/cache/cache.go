@@ -0,0 +1,18 @@
+// Package cache provides the stores of queries registered by clients as
+// automatic persisted queries.
+package cache
+
+import (
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+// New returns a cache in the configured Redis server, or an LRU cache in
+// process if there is none.
+func New(cfg config.PersistedQueries) graphql.Cache {
+	if cfg.Redis.Address == "" {
+		return lru.New(cfg.CacheSize)
+	}
+	return &redisCache{cfg: cfg.Redis}
+}
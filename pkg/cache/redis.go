@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+const (
+	// timeout bounds a command including dialing, so that a slow server
+	// delays operations by no more than a cache miss would.
+	timeout = 2 * time.Second
+	// maxIdle is how many connections are kept for reuse.
+	maxIdle = 4
+)
+
+// redisCache stores strings in Redis, speaking just enough of its
+// protocol to GET and SET them. Failures are logged and treated as
+// misses, as clients then send the full query again.
+type redisCache struct {
+	cfg config.Redis
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	v, err := c.do(ctx, "GET", c.cfg.Prefix+key)
+	if err != nil {
+		log.Printf("persisted query cache: %v", err)
+		return nil, false
+	}
+	if v == nil {
+		return nil, false
+	}
+	return *v, true
+}
+
+func (c *redisCache) Add(ctx context.Context, key string, value interface{}) {
+	s, ok := value.(string)
+	if !ok {
+		return
+	}
+	args := []string{"SET", c.cfg.Prefix + key, s}
+	if c.cfg.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.cfg.TTL.Milliseconds(), 10))
+	}
+	if _, err := c.do(ctx, args...); err != nil {
+		log.Printf("persisted query cache: %v", err)
+	}
+}
+
+// do sends a command on an idle or new connection and returns the reply,
+// nil for a null bulk string.
+func (c *redisCache) do(ctx context.Context, args ...string) (*string, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	v, err := conn.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is left in an unknown state.
+		conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+	return v, err
+}
+
+func (c *redisCache) get(ctx context.Context) (*redisConn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	d := net.Dialer{Timeout: timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if c.cfg.Password != "" {
+		auth := []string{"AUTH", c.cfg.Password}
+		if c.cfg.Username != "" {
+			auth = []string{"AUTH", c.cfg.Username, c.cfg.Password}
+		}
+		if _, err := conn.do(auth...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisCache) put(conn *redisConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.idle) >= maxIdle {
+		conn.Close()
+		return
+	}
+	c.idle = append(c.idle, conn)
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// redisError is an error replied by the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) do(args ...string) (*string, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		buf = append(buf, a...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.reply()
+}
+
+// reply reads a simple string, error, integer or bulk string.
+func (c *redisConn) reply() (*string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+', ':':
+		return &line, nil
+	case '-':
+		return nil, redisError(line)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed reply length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		s := string(buf[:n])
+		return &s, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", kind)
+	}
+}
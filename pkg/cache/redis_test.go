@@ -0,0 +1,340 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+// fakeRedis is a Redis server on a local port that replies to commands
+// with what reply returns, the raw RESP reply or "" to close the
+// connection instead.
+type fakeRedis struct {
+	ln    net.Listener
+	reply func(args []string) string
+
+	mu       sync.Mutex
+	commands [][]string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, reply func(args []string) string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeRedis{ln: ln, reply: reply}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *fakeRedis) handle(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+		reply := s.reply(args)
+		if reply == "" {
+			return
+		}
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("not an array")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (s *fakeRedis) cache(cfg config.Redis) *redisCache {
+	cfg.Address = s.ln.Addr().String()
+	return &redisCache{cfg: cfg}
+}
+
+func (s *fakeRedis) received() ([][]string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...), s.conns
+}
+
+// mapReplies replies to GET and SET like a server holding values.
+func mapReplies() func(args []string) string {
+	var mu sync.Mutex
+	values := map[string]string{}
+	return func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			v, ok := values[args[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+		case "SET":
+			values[args[1]] = args[2]
+			return "+OK\r\n"
+		}
+		return "+OK\r\n"
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	s := newFakeRedis(t, mapReplies())
+	c := s.cache(config.Redis{Prefix: "apq:", TTL: time.Minute})
+	ctx := context.Background()
+
+	if v, ok := c.Get(ctx, "q"); ok {
+		t.Fatalf("Get of a missing key = %v, want a miss", v)
+	}
+	c.Add(ctx, "q", "{ me { id } }\r\nwith a line break")
+	v, ok := c.Get(ctx, "q")
+	if !ok || v != "{ me { id } }\r\nwith a line break" {
+		t.Fatalf("Get = %q, %v, want the value added", v, ok)
+	}
+	c.Add(ctx, "ignored", 42)
+
+	commands, conns := s.received()
+	want := [][]string{
+		{"GET", "apq:q"},
+		{"SET", "apq:q", "{ me { id } }\r\nwith a line break", "PX", "60000"},
+		{"GET", "apq:q"},
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+	if conns != 1 {
+		t.Errorf("%d connections, want the first one reused", conns)
+	}
+}
+
+func TestRedisCacheConnect(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Redis
+		want [][]string
+	}{
+		{"plain", config.Redis{}, nil},
+		{"password", config.Redis{Password: "secret"}, [][]string{{"AUTH", "secret"}}},
+		{"user", config.Redis{Username: "oaf", Password: "secret"}, [][]string{{"AUTH", "oaf", "secret"}}},
+		{"db", config.Redis{DB: 3}, [][]string{{"SELECT", "3"}}},
+		{"all", config.Redis{Username: "oaf", Password: "secret", DB: 3}, [][]string{{"AUTH", "oaf", "secret"}, {"SELECT", "3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeRedis(t, mapReplies())
+			c := s.cache(tt.cfg)
+			c.Get(context.Background(), "q")
+			commands, _ := s.received()
+			want := append(tt.want, []string{"GET", "q"})
+			if !reflect.DeepEqual(commands, want) {
+				t.Errorf("commands = %q, want %q", commands, want)
+			}
+		})
+	}
+}
+
+func TestRedisCacheAuthFailure(t *testing.T) {
+	s := newFakeRedis(t, func(args []string) string {
+		if args[0] == "AUTH" {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		return "$-1\r\n"
+	})
+	c := s.cache(config.Redis{Password: "wrong"})
+	if _, ok := c.Get(context.Background(), "q"); ok {
+		t.Fatal("Get succeeded without authentication")
+	}
+	if commands, _ := s.received(); len(commands) != 1 {
+		t.Errorf("commands = %q, want none after AUTH failed", commands)
+	}
+	if len(c.idle) != 0 {
+		t.Error("the unauthenticated connection is kept")
+	}
+}
+
+func TestRedisCacheErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		// reused tells whether the connection is kept after the reply.
+		reused bool
+	}{
+		{"error reply", "-ERR unknown command\r\n", true},
+		{"closed", "", false},
+		{"malformed", "OK\r\n", false},
+		{"missing CR", "+OK\n", false},
+		{"bad length", "$abc\r\n", false},
+		{"short bulk", "$10\r\nabc\r\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := tt.reply
+			s := newFakeRedis(t, func(args []string) string { return reply })
+			c := s.cache(config.Redis{})
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			if v, ok := c.Get(ctx, "q"); ok {
+				t.Fatalf("Get = %v, want a miss", v)
+			}
+			if reused := len(c.idle) == 1; reused != tt.reused {
+				t.Errorf("connection kept = %v, want %v", reused, tt.reused)
+			}
+		})
+	}
+}
+
+func TestRedisCacheReconnect(t *testing.T) {
+	values := mapReplies()
+	var mu sync.Mutex
+	drop := false
+	s := newFakeRedis(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if drop {
+			drop = false
+			return ""
+		}
+		return values(args)
+	})
+	c := s.cache(config.Redis{})
+	ctx := context.Background()
+
+	c.Add(ctx, "q", "query")
+	mu.Lock()
+	drop = true
+	mu.Unlock()
+	if _, ok := c.Get(ctx, "q"); ok {
+		t.Fatal("Get succeeded on a connection the server closed")
+	}
+	if v, ok := c.Get(ctx, "q"); !ok || v != "query" {
+		t.Fatalf("Get after the server closed the connection = %v, %v, want the value", v, ok)
+	}
+	if _, conns := s.received(); conns != 2 {
+		t.Errorf("%d connections, want 2", conns)
+	}
+}
+
+func TestRedisCacheUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c := &redisCache{cfg: config.Redis{Address: addr}}
+	if _, ok := c.Get(context.Background(), "q"); ok {
+		t.Fatal("Get succeeded without a server")
+	}
+	c.Add(context.Background(), "q", "query")
+}
+
+func TestReply(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		in      string
+		want    *string
+		wantErr string
+	}{
+		{"+OK\r\n", str("OK"), ""},
+		{":42\r\n", str("42"), ""},
+		{"$5\r\nhello\r\n", str("hello"), ""},
+		{"$0\r\n\r\n", str(""), ""},
+		{"$7\r\na\r\nb\r\nc\r\n", str("a\r\nb\r\nc"), ""},
+		{"$-1\r\n", nil, ""},
+		{"-ERR wrong type\r\n", nil, "redis: ERR wrong type"},
+		{"*1\r\n", nil, `redis: unexpected reply '*'`},
+		{"$x\r\n", nil, `redis: malformed reply length "x"`},
+		{"+OK\n", nil, `redis: malformed reply "+OK\n"`},
+		{"$5\r\nhel", nil, "unexpected EOF"},
+		{"", nil, "EOF"},
+	}
+	for _, tt := range tests {
+		c := &redisConn{r: bufio.NewReader(strings.NewReader(tt.in))}
+		got, err := c.reply()
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("reply(%q) = %v, %q, want %v, %q", tt.in, deref(got), gotErr, deref(tt.want), tt.wantErr)
+		}
+	}
+}
+
+func TestReplyErrorKept(t *testing.T) {
+	c := &redisConn{r: bufio.NewReader(strings.NewReader("-ERR x\r\n"))}
+	_, err := c.reply()
+	var replyErr redisError
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("reply error = %T, want a redisError", err)
+	}
+}
+
+func deref(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
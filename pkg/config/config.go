@@ -0,0 +1,278 @@
+// Package config loads the oaf-server configuration file.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the root of the configuration file.
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Invites  Invites  `yaml:"invites"`
+	Mail     Mail     `yaml:"mail"`
+	Calendar Calendar `yaml:"calendar"`
+}
+
+// Server configures the HTTP listener.
+type Server struct {
+	// Address is the host:port the server listens on.
+	Address string `yaml:"address"`
+	// Playground mounts the GraphQL playground at / when enabled.
+	Playground bool `yaml:"playground"`
+	// ShutdownTimeout bounds how long in-flight requests may take to
+	// finish after a termination signal.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	TLS             TLS           `yaml:"tls"`
+	Limits          Limits        `yaml:"limits"`
+	// PersistedQueries configures operations sent by hash.
+	PersistedQueries PersistedQueries `yaml:"persistedQueries"`
+}
+
+// TLS holds the certificate used to serve HTTPS. TLS is disabled when
+// both files are empty.
+type TLS struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// Enabled reports whether a certificate has been configured.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Limits bounds the operations clients may run, as the cycles in the
+// schema allow queries of any size.
+type Limits struct {
+	// MaxComplexity bounds the estimated cost of an operation, the sum of
+	// the costs of its fields, with the fields below a list counted once
+	// per item. Zero disables the limit.
+	MaxComplexity int `yaml:"maxComplexity"`
+	// MaxDepth bounds how deeply the fields of an operation nest. Zero
+	// disables the limit.
+	MaxDepth int `yaml:"maxDepth"`
+	// ListSize is the number of items assumed for lists whose size the
+	// operation does not give, such as connections without first or last.
+	ListSize int `yaml:"listSize"`
+	// Costs overrides the cost of fields, which is 1 by default. Keys are
+	// of the form Type.field, such as Event.occurrences.
+	Costs map[string]int `yaml:"costs"`
+}
+
+// PersistedQueries configures automatic persisted queries, which clients
+// register once and then send by their SHA-256 hash, and the manifest of
+// operations known in advance.
+type PersistedQueries struct {
+	// CacheSize is how many registered queries are kept in process if no
+	// Redis server is configured.
+	CacheSize int `yaml:"cacheSize"`
+	// Redis keeps registered queries in a Redis server shared by all
+	// instances if its address is set.
+	Redis Redis `yaml:"redis"`
+	// Manifest is the path of a JSON file holding operations clients may
+	// send by hash without registering them: an object mapping hashes to
+	// queries, or an Apollo persisted query manifest.
+	Manifest string `yaml:"manifest"`
+	// Strict rejects every operation not in the manifest, so that clients
+	// can run the operations shipped with them and nothing else.
+	Strict bool `yaml:"strict"`
+}
+
+// Redis configures the connection to a Redis server.
+type Redis struct {
+	// Address is the host:port of the server.
+	Address string `yaml:"address"`
+	// Username and Password authenticate with the server if set.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// DB is the number of the database used.
+	DB int `yaml:"db"`
+	// Prefix is prepended to keys, to share the database with others.
+	Prefix string `yaml:"prefix"`
+	// TTL is how long entries are kept, forever if zero.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Storage drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Database selects the storage backend.
+type Database struct {
+	// Driver is one of the Driver constants. The memory driver keeps all
+	// data in process and needs no DSN.
+	Driver string `yaml:"driver"`
+	// DSN is the driver specific connection string. For SQLite it is the
+	// path of the database file.
+	DSN string `yaml:"dsn"`
+	// AutoMigrate applies pending migrations on start. When disabled the
+	// server refuses to start until `oaf-server migrate up` has been run.
+	AutoMigrate bool `yaml:"autoMigrate"`
+}
+
+// Auth configures the tokens issued by the login mutation.
+type Auth struct {
+	// Secret is the HMAC key access tokens are signed with. It must be at
+	// least 32 bytes long.
+	Secret string `yaml:"secret"`
+	// AccessTokenTTL is the lifetime of access tokens.
+	AccessTokenTTL time.Duration `yaml:"accessTokenTTL"`
+	// RefreshTokenTTL is the lifetime of refresh tokens.
+	RefreshTokenTTL time.Duration `yaml:"refreshTokenTTL"`
+}
+
+// Invites configures invitations to sections.
+type Invites struct {
+	// TTL is how long an invite can be accepted.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Mail configures how mails, such as invites by email, are sent. Without
+// an SMTP server they are written to the log.
+type Mail struct {
+	// SMTPAddress is the host:port of the SMTP server.
+	SMTPAddress string `yaml:"smtpAddress"`
+	// Username and Password authenticate with the SMTP server if set.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender address.
+	From string `yaml:"from"`
+}
+
+// Calendar configures the iCalendar feeds of events.
+type Calendar struct {
+	// Reminder is how long before an event calendar applications remind
+	// of it. Zero disables reminders.
+	Reminder time.Duration `yaml:"reminder"`
+}
+
+// Default returns the configuration used when no file is given.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:         ":8080",
+			ShutdownTimeout: 10 * time.Second,
+			Limits: Limits{
+				MaxComplexity: 50000,
+				MaxDepth:      15,
+				ListSize:      100,
+			},
+			PersistedQueries: PersistedQueries{
+				CacheSize: 1000,
+				Redis: Redis{
+					Prefix: "oaf-server:apq:",
+				},
+			},
+		},
+		Database: Database{
+			Driver: DriverPostgres,
+		},
+		Auth: Auth{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		Invites: Invites{
+			TTL: 14 * 24 * time.Hour,
+		},
+		Calendar: Calendar{
+			Reminder: time.Hour,
+		},
+	}
+}
+
+// Load reads the YAML file at path on top of the defaults.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, cfg.validate()
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, cfg.validate()
+}
+
+func (c Config) validate() error {
+	if c.Server.Address == "" {
+		return fmt.Errorf("server.address must not be empty")
+	}
+	if c.Server.TLS.Enabled() && (c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server.tls requires both certFile and keyFile")
+	}
+	if err := c.Server.Limits.validate(); err != nil {
+		return err
+	}
+	if err := c.Server.PersistedQueries.validate(); err != nil {
+		return err
+	}
+	switch c.Database.Driver {
+	case DriverPostgres, DriverSQLite:
+		if c.Database.DSN == "" {
+			return fmt.Errorf("database.dsn must not be empty")
+		}
+	case DriverMemory:
+	default:
+		return fmt.Errorf("unknown database.driver %q", c.Database.Driver)
+	}
+	if len(c.Auth.Secret) < 32 {
+		return fmt.Errorf("auth.secret must be at least 32 bytes long")
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		return fmt.Errorf("auth token lifetimes must be positive")
+	}
+	if c.Invites.TTL <= 0 {
+		return fmt.Errorf("invites.ttl must be positive")
+	}
+	if c.Calendar.Reminder < 0 {
+		return fmt.Errorf("calendar.reminder must not be negative")
+	}
+	if c.Mail.SMTPAddress != "" && c.Mail.From == "" {
+		return fmt.Errorf("mail.from must be set when mail.smtpAddress is")
+	}
+	return nil
+}
+
+func (l Limits) validate() error {
+	if l.MaxComplexity < 0 || l.MaxDepth < 0 {
+		return fmt.Errorf("server.limits must not be negative")
+	}
+	if l.ListSize <= 0 {
+		return fmt.Errorf("server.limits.listSize must be positive")
+	}
+	for field, cost := range l.Costs {
+		if parts := strings.Split(field, "."); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("server.limits.costs: %q is not of the form Type.field", field)
+		}
+		if cost < 0 {
+			return fmt.Errorf("server.limits.costs: cost of %s must not be negative", field)
+		}
+	}
+	return nil
+}
+
+func (p PersistedQueries) validate() error {
+	if p.Strict && p.Manifest == "" {
+		return fmt.Errorf("server.persistedQueries.strict requires a manifest")
+	}
+	if p.Redis.Address == "" && p.CacheSize <= 0 {
+		return fmt.Errorf("server.persistedQueries.cacheSize must be positive")
+	}
+	if p.Redis.DB < 0 || p.Redis.TTL < 0 {
+		return fmt.Errorf("server.persistedQueries.redis: db and ttl must not be negative")
+	}
+	return nil
+}
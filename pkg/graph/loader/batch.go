@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fetchFunc returns the values of the given keys. Keys missing from the
+// result are reported to their callers by the batcher.
+type fetchFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// batcher collects the keys requested within wait of the first one, up
+// to maxBatch of them, and fetches them with a single call. Results are
+// cached for the lifetime of the batcher, errors included.
+type batcher struct {
+	fetch fetchFunc
+	// missing is the result of keys the fetch does not return.
+	missing func(key string) (interface{}, error)
+	wait    time.Duration
+
+	mu      sync.Mutex
+	cache   map[string]*result
+	pending *batch
+}
+
+type batch struct {
+	keys       []string
+	results    []*result
+	dispatched bool
+}
+
+type result struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newBatcher(fetch fetchFunc, missing func(string) (interface{}, error), wait time.Duration) *batcher {
+	return &batcher{fetch: fetch, missing: missing, wait: wait, cache: map[string]*result{}}
+}
+
+// load returns the value of key.
+func (b *batcher) load(ctx context.Context, key string) (interface{}, error) {
+	b.mu.Lock()
+	res := b.enqueue(ctx, key)
+	b.mu.Unlock()
+	return res.get(ctx)
+}
+
+// loadAll returns the values of keys, fetched in the same batch. It
+// waits for all of them even if some fail, and returns the first error.
+func (b *batcher) loadAll(ctx context.Context, keys []string) ([]interface{}, error) {
+	b.mu.Lock()
+	results := make([]*result, len(keys))
+	for i, key := range keys {
+		results[i] = b.enqueue(ctx, key)
+	}
+	b.mu.Unlock()
+
+	values := make([]interface{}, len(keys))
+	var first error
+	for i, res := range results {
+		v, err := res.get(ctx)
+		if err != nil && first == nil {
+			first = err
+		}
+		values[i] = v
+	}
+	if first != nil {
+		return nil, first
+	}
+	return values, nil
+}
+
+// enqueue returns the cached result of key or adds key to the pending
+// batch. It expects b.mu to be held.
+func (b *batcher) enqueue(ctx context.Context, key string) *result {
+	if res, ok := b.cache[key]; ok {
+		return res
+	}
+	res := &result{done: make(chan struct{})}
+	b.cache[key] = res
+
+	if b.pending == nil {
+		p := &batch{}
+		b.pending = p
+		time.AfterFunc(b.wait, func() { b.dispatch(ctx, p) })
+	}
+	p := b.pending
+	p.keys = append(p.keys, key)
+	p.results = append(p.results, res)
+	if len(p.keys) == maxBatch {
+		b.pending = nil
+		go b.dispatch(ctx, p)
+	}
+	return res
+}
+
+// dispatch fetches the keys of p unless that has been done already.
+func (b *batcher) dispatch(ctx context.Context, p *batch) {
+	b.mu.Lock()
+	if b.pending == p {
+		b.pending = nil
+	}
+	if p.dispatched {
+		b.mu.Unlock()
+		return
+	}
+	p.dispatched = true
+	b.mu.Unlock()
+
+	values, err := b.fetch(ctx, p.keys)
+	for i, key := range p.keys {
+		res := p.results[i]
+		switch v, ok := values[key]; {
+		case err != nil:
+			res.err = err
+		case ok:
+			res.value = v
+		default:
+			res.value, res.err = b.missing(key)
+		}
+		close(res.done)
+	}
+}
+
+func (res *result) get(ctx context.Context) (interface{}, error) {
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
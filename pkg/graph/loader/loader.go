@@ -0,0 +1,297 @@
+// Package loader batches and caches the storage lookups of a GraphQL
+// operation. Resolving a relation for every element of a list, such as the
+// user of each attendee, then costs one query for the whole list instead
+// of one per element.
+package loader
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// wait is how long keys are collected before a batch is fetched.
+	wait = time.Millisecond
+	// maxBatch bounds the keys fetched at once, keeping queries below the
+	// parameter limits of the databases.
+	maxBatch = 500
+)
+
+// Loaders holds one loader per kind of lookup. As their caches are never
+// invalidated, Loaders are meant to live no longer than an operation.
+type Loaders struct {
+	Users         *UserLoader
+	Organizations *OrganizationLoader
+	Sections      *SectionLoader
+	Events        *EventLoader
+	// Replacements loads the events replacing occurrences of recurring
+	// events by series ID.
+	Replacements *ReplacementLoader
+	// Attendees loads the attendees of events by event ID.
+	Attendees *AttendeeLoader
+	// Members loads the members of sections by section ID.
+	Members *MemberLoader
+}
+
+// New returns Loaders fetching from store.
+func New(store *storage.Store) *Loaders {
+	return &Loaders{
+		Users: &UserLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			users, err := store.Users.GetMany(ctx, ids)
+			values := make(map[string]interface{}, len(users))
+			for _, u := range users {
+				values[u.ID] = u
+			}
+			return values, err
+		}, notFound, wait)},
+		Organizations: &OrganizationLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			organizations, err := store.Organizations.GetMany(ctx, ids)
+			values := make(map[string]interface{}, len(organizations))
+			for _, o := range organizations {
+				values[o.ID] = o
+			}
+			return values, err
+		}, notFound, wait)},
+		Sections: &SectionLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			sections, err := store.Sections.GetMany(ctx, ids)
+			values := make(map[string]interface{}, len(sections))
+			for _, s := range sections {
+				values[s.ID] = s
+			}
+			return values, err
+		}, notFound, wait)},
+		Events: &EventLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			events, err := store.Events.GetMany(ctx, ids)
+			values := make(map[string]interface{}, len(events))
+			for _, e := range events {
+				values[e.ID] = e
+			}
+			return values, err
+		}, notFound, wait)},
+		Replacements: &ReplacementLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			events, err := store.Events.List(ctx, storage.EventFilter{SeriesIDs: ids})
+			values := make(map[string]interface{}, len(ids))
+			for _, e := range events {
+				list, _ := values[*e.SeriesID].([]*model.Event)
+				values[*e.SeriesID] = append(list, e)
+			}
+			return values, err
+		}, none, wait)},
+		Attendees: &AttendeeLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			attendees, err := store.Attendees.List(ctx, storage.AttendeeFilter{EventIDs: ids})
+			values := make(map[string]interface{}, len(ids))
+			for _, a := range attendees {
+				list, _ := values[a.EventID].([]*model.Attendee)
+				values[a.EventID] = append(list, a)
+			}
+			return values, err
+		}, none, wait)},
+		Members: &MemberLoader{newBatcher(func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+			members, err := store.Members.List(ctx, storage.MemberFilter{SectionIDs: ids})
+			values := make(map[string]interface{}, len(ids))
+			for _, m := range members {
+				list, _ := values[m.SectionID].([]*model.Member)
+				values[m.SectionID] = append(list, m)
+			}
+			return values, err
+		}, none, wait)},
+	}
+}
+
+// notFound is the result of unknown IDs, as returned by the Get methods
+// of the store.
+func notFound(string) (interface{}, error) {
+	return nil, storage.ErrNotFound
+}
+
+// none is the result of keys without values in one-to-many lookups.
+func none(string) (interface{}, error) {
+	return nil, nil
+}
+
+type UserLoader struct{ b *batcher }
+
+func (l *UserLoader) Load(ctx context.Context, id string) (*model.User, error) {
+	v, err := l.b.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.User), nil
+}
+
+// LoadAll returns the users with the given IDs in the same order.
+func (l *UserLoader) LoadAll(ctx context.Context, ids []string) ([]*model.User, error) {
+	values, err := l.b.loadAll(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*model.User, len(values))
+	for i, v := range values {
+		users[i] = v.(*model.User)
+	}
+	return users, nil
+}
+
+type OrganizationLoader struct{ b *batcher }
+
+func (l *OrganizationLoader) Load(ctx context.Context, id string) (*model.Organization, error) {
+	v, err := l.b.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.Organization), nil
+}
+
+// LoadAll returns the organizations with the given IDs in the same order.
+func (l *OrganizationLoader) LoadAll(ctx context.Context, ids []string) ([]*model.Organization, error) {
+	values, err := l.b.loadAll(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	organizations := make([]*model.Organization, len(values))
+	for i, v := range values {
+		organizations[i] = v.(*model.Organization)
+	}
+	return organizations, nil
+}
+
+type SectionLoader struct{ b *batcher }
+
+func (l *SectionLoader) Load(ctx context.Context, id string) (*model.Section, error) {
+	v, err := l.b.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.Section), nil
+}
+
+// LoadAll returns the sections with the given IDs in the same order.
+func (l *SectionLoader) LoadAll(ctx context.Context, ids []string) ([]*model.Section, error) {
+	values, err := l.b.loadAll(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]*model.Section, len(values))
+	for i, v := range values {
+		sections[i] = v.(*model.Section)
+	}
+	return sections, nil
+}
+
+// EventLoader loads stored events, which callers must not modify as they
+// are shared with the rest of the operation.
+type EventLoader struct{ b *batcher }
+
+func (l *EventLoader) Load(ctx context.Context, id string) (*model.Event, error) {
+	v, err := l.b.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.Event), nil
+}
+
+// Prefetch loads the events with the given IDs in one batch, so that
+// loading them one at a time afterwards does not wait for a batch each.
+// Errors are left for those loads to return.
+func (l *EventLoader) Prefetch(ctx context.Context, ids []string) {
+	l.b.loadAll(ctx, ids)
+}
+
+// ReplacementLoader loads stored events like EventLoader does.
+type ReplacementLoader struct{ b *batcher }
+
+// Load returns the events replacing occurrences of the recurring event
+// with the given ID.
+func (l *ReplacementLoader) Load(ctx context.Context, seriesID string) ([]*model.Event, error) {
+	v, err := l.b.load(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	events, _ := v.([]*model.Event)
+	return events, nil
+}
+
+// LoadAll returns the events replacing occurrences of each of the
+// recurring events with the given IDs, in the same order.
+func (l *ReplacementLoader) LoadAll(ctx context.Context, seriesIDs []string) ([][]*model.Event, error) {
+	values, err := l.b.loadAll(ctx, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	events := make([][]*model.Event, len(values))
+	for i, v := range values {
+		events[i], _ = v.([]*model.Event)
+	}
+	return events, nil
+}
+
+type AttendeeLoader struct{ b *batcher }
+
+// Load returns the attendees of the event with the given ID, of all its
+// occurrences if it recurs, ordered like AttendeeRepository.List does.
+func (l *AttendeeLoader) Load(ctx context.Context, eventID string) ([]*model.Attendee, error) {
+	v, err := l.b.load(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	attendees, _ := v.([]*model.Attendee)
+	return attendees, nil
+}
+
+type MemberLoader struct{ b *batcher }
+
+// Load returns the members of the section with the given ID, ordered like
+// MemberRepository.List does.
+func (l *MemberLoader) Load(ctx context.Context, sectionID string) ([]*model.Member, error) {
+	v, err := l.b.load(ctx, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	members, _ := v.([]*model.Member)
+	return members, nil
+}
+
+type contextKey struct{}
+
+// WithLoaders returns a copy of ctx carrying l.
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Loaders carried by ctx, if any.
+func FromContext(ctx context.Context) (*Loaders, bool) {
+	l, ok := ctx.Value(contextKey{}).(*Loaders)
+	return l, ok
+}
+
+// Extension gives every query its own Loaders. Mutations and subscriptions
+// go without, so that what they return after a change is not resolved from
+// a cache filled before it, as by an earlier root field of the mutation.
+type Extension struct {
+	Store *storage.Store
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = Extension{}
+
+func (Extension) ExtensionName() string {
+	return "Loaders"
+}
+
+func (Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (e Extension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if graphql.GetOperationContext(ctx).Operation.Operation == ast.Query {
+		ctx = WithLoaders(ctx, New(e.Store))
+	}
+	return next(ctx)
+}
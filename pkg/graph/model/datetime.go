@@ -0,0 +1,32 @@
+package model
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+)
+
+// MarshalDateTime writes t as an RFC 3339 string in the location of t.
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.Format(time.RFC3339)))
+	})
+}
+
+// UnmarshalDateTime reads an RFC 3339 string, which must carry a UTC
+// offset. The time is returned in UTC and truncated to the second, which
+// is the precision events are kept at.
+func UnmarshalDateTime(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, apperr.New(apperr.Validation, "DateTime must be a string")
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, apperr.New(apperr.Validation, "DateTime must be an RFC 3339 date and time with offset, e.g. 2006-01-02T15:04:05+02:00")
+	}
+	return t.UTC().Truncate(time.Second), nil
+}
@@ -0,0 +1,15 @@
+package model
+
+import "github.com/concertLabs/oaf-server/pkg/relay"
+
+// The id fields of the schema are bound to these methods, so clients only
+// ever see global IDs. See gqlgen.yml.
+
+func (u User) GlobalID() string         { return relay.ToGlobalID("User", u.ID) }
+func (o Organization) GlobalID() string { return relay.ToGlobalID("Organization", o.ID) }
+func (s Section) GlobalID() string      { return relay.ToGlobalID("Section", s.ID) }
+func (m Member) GlobalID() string       { return relay.ToGlobalID("Member", m.ID) }
+func (e Event) GlobalID() string        { return relay.ToGlobalID("Event", e.Key()) }
+func (c Comment) GlobalID() string      { return relay.ToGlobalID("Comment", c.ID) }
+func (a Attendee) GlobalID() string     { return relay.ToGlobalID("Attendee", a.ID) }
+func (i Invite) GlobalID() string       { return relay.ToGlobalID("Invite", i.ID) }
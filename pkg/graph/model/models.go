@@ -0,0 +1,166 @@
+package model
+
+import "time"
+
+// The types in this file replace the generated ones for entities that
+// reference other entities. They carry the foreign keys as stored in the
+// database; the referenced objects are loaded by field resolvers.
+
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// Password is the bcrypt hash of the password. It is never exposed
+	// through the API.
+	Password  string  `json:"-"`
+	Email     string  `json:"email"`
+	Showname  *string `json:"showname"`
+	Superuser bool    `json:"superuser"`
+	// CalendarTokenHash is the hash of the token the user's calendar
+	// feeds are fetched with, nil if there is none.
+	CalendarTokenHash *string `json:"-"`
+}
+
+func (User) IsNode() {}
+
+type Organization struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Picture *string `json:"picture"`
+	// Timezone is the IANA name of the location event times are shown in
+	// by default.
+	Timezone string `json:"timezone"`
+}
+
+func (Organization) IsNode() {}
+
+type Section struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	OrganizationID string `json:"-"`
+}
+
+func (Section) IsNode() {}
+
+type Member struct {
+	ID        string `json:"id"`
+	UserID    string `json:"-"`
+	SectionID string `json:"-"`
+	Right     int    `json:"right"`
+}
+
+func (Member) IsNode() {}
+
+type Event struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	Adress      *string    `json:"adress"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end"`
+	CreatorID   string     `json:"-"`
+	// OrganizationID is nil for events created before events belonged to
+	// organizations.
+	OrganizationID *string `json:"-"`
+	// SectionIDs lists the sections the event is for, ordered by ID. The
+	// event is for the whole organization if there are none.
+	SectionIDs []string `json:"-"`
+	// Recurrence is the RFC 5545 recurrence rule of a recurring event,
+	// such as FREQ=WEEKLY;BYDAY=TU. It is nil for single events.
+	Recurrence *string `json:"recurrence"`
+	// ExDates are the starts of the occurrences removed from a recurring
+	// event, in order.
+	ExDates []time.Time `json:"exdates"`
+	// SeriesID is set on events replacing a single occurrence of the
+	// recurring event with that ID.
+	SeriesID *string `json:"-"`
+	// RecurrenceID is the original start of the occurrence the event is.
+	// Besides on events replacing an occurrence it is set on the
+	// occurrences recurring events are expanded to, which are not stored
+	// and share the ID of their series.
+	RecurrenceID *time.Time `json:"recurrenceId"`
+	// UID is the UID of the iCalendar event the event was imported from,
+	// unique within its organization.
+	UID *string `json:"uid"`
+}
+
+func (Event) IsNode() {}
+
+// IsOccurrence reports whether e is an occurrence expanded from a
+// recurring event rather than a stored event.
+func (e Event) IsOccurrence() bool {
+	return e.SeriesID == nil && e.RecurrenceID != nil
+}
+
+// RecurrenceIDFormat is the layout of the start of an occurrence in its
+// Key.
+const RecurrenceIDFormat = "20060102T150405Z"
+
+// Key is the primary key of the event, which for occurrences is the ID of
+// their series followed by an @ and their original start.
+func (e Event) Key() string {
+	if e.IsOccurrence() {
+		return e.ID + "@" + e.RecurrenceID.UTC().Format(RecurrenceIDFormat)
+	}
+	return e.ID
+}
+
+type Comment struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	CreatorID string `json:"-"`
+	EventID   string `json:"-"`
+	// CreatedAt is set by the store, to the second.
+	CreatedAt time.Time `json:"-"`
+}
+
+func (Comment) IsNode() {}
+
+type Attendee struct {
+	ID      string `json:"id"`
+	UserID  string `json:"-"`
+	EventID string `json:"-"`
+	// Occurrence is the start of the occurrence of the recurring event
+	// EventID the response is for. Responses to single events have none.
+	Occurrence *time.Time `json:"-"`
+	Commitment Commitment `json:"Commitment"`
+	Comment    *string    `json:"Comment"`
+}
+
+func (Attendee) IsNode() {}
+
+type Invite struct {
+	ID string `json:"id"`
+	// Exactly one of UserID and Email is set. Invites by email are
+	// redeemed with a token when the invitee signs up.
+	UserID    *string `json:"-"`
+	Email     *string `json:"email"`
+	SectionID string  `json:"-"`
+	// InvitedByID is nil for invites created before the inviter was
+	// recorded, or whose inviter has been deleted.
+	InvitedByID *string `json:"-"`
+	// Right is the bit set of rights the invitee becomes a member with.
+	Right int `json:"right"`
+	// ExpiresAt is nil for invites created before invites expired.
+	ExpiresAt *time.Time `json:"expiresAt"`
+	// TokenHash is the hash of the token sent to invites by email.
+	TokenHash *string `json:"-"`
+}
+
+// Expired reports whether the invite can no longer be accepted at now.
+func (i *Invite) Expired(now time.Time) bool {
+	return i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)
+}
+
+func (Invite) IsNode() {}
+
+// Viewer holds the authenticated user; everything else on it is resolved
+// on demand.
+type Viewer struct {
+	User *User `json:"user"`
+}
+
+// HasCalendarToken reports whether the user has a token for calendar
+// feeds.
+func (v *Viewer) HasCalendarToken() bool {
+	return v.User.CalendarTokenHash != nil
+}
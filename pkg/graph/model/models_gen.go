@@ -6,83 +6,125 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
 )
 
 type Node interface {
 	IsNode()
 }
 
-type Attendee struct {
-	ID         string     `json:"id"`
-	User       *User      `json:"user"`
-	Event      *Event     `json:"event"`
-	Commitment Commitment `json:"Commitment"`
-	Comment    *string    `json:"Comment"`
+type AttendeeChange struct {
+	Kind     ChangeKind `json:"kind"`
+	Attendee *Attendee  `json:"attendee"`
 }
 
-func (Attendee) IsNode() {}
+type AttendeeConnection struct {
+	Edges      []*AttendeeEdge `json:"edges"`
+	PageInfo   *PageInfo       `json:"pageInfo"`
+	TotalCount int             `json:"totalCount"`
+}
+
+type AttendeeEdge struct {
+	Cursor string    `json:"cursor"`
+	Node   *Attendee `json:"node"`
+}
+
+type AuthPayload struct {
+	AccessToken  string `json:"accessToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+	User         *User  `json:"user"`
+}
 
-type Comment struct {
-	ID      string `json:"id"`
-	Text    string `json:"text"`
-	Creator *User  `json:"creator"`
-	Event   *Event `json:"event"`
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
 }
 
-func (Comment) IsNode() {}
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
 
-type Event struct {
-	ID          string      `json:"id"`
-	Name        string      `json:"name"`
-	Description *string     `json:"description"`
-	Adress      *string     `json:"adress"`
-	Start       string      `json:"start"`
-	End         *string     `json:"end"`
-	Creator     *User       `json:"creator"`
-	Comments    []*Comment  `json:"comments"`
-	Attendees   []*Attendee `json:"attendees"`
+type EventConnection struct {
+	Edges      []*EventEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
 }
 
-func (Event) IsNode() {}
+type EventEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Event `json:"node"`
+}
 
-type Invite struct {
-	ID      string   `json:"id"`
-	User    *User    `json:"user"`
-	Section *Section `json:"section"`
+type ImportResult struct {
+	DryRun  bool             `json:"dryRun"`
+	Created int              `json:"created"`
+	Events  []*ImportedEvent `json:"events"`
 }
 
-func (Invite) IsNode() {}
+type ImportedEvent struct {
+	UID               *string      `json:"uid"`
+	Name              *string      `json:"name"`
+	Start             *time.Time   `json:"start"`
+	Status            ImportStatus `json:"status"`
+	Message           *string      `json:"message"`
+	DuplicateOf       *Event       `json:"duplicateOf"`
+	Conflicts         []*Event     `json:"conflicts"`
+	CalendarConflicts []int        `json:"calendarConflicts"`
+	Event             *Event       `json:"event"`
+}
+
+type InviteConnection struct {
+	Edges      []*InviteEdge `json:"edges"`
+	PageInfo   *PageInfo     `json:"pageInfo"`
+	TotalCount int           `json:"totalCount"`
+}
+
+type InviteEdge struct {
+	Cursor string  `json:"cursor"`
+	Node   *Invite `json:"node"`
+}
 
 type Login struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
-type Member struct {
-	ID      string   `json:"id"`
-	User    *User    `json:"user"`
-	Section *Section `json:"section"`
-	Right   int      `json:"right"`
+type MemberConnection struct {
+	Edges      []*MemberEdge `json:"edges"`
+	PageInfo   *PageInfo     `json:"pageInfo"`
+	TotalCount int           `json:"totalCount"`
 }
 
-func (Member) IsNode() {}
+type MemberEdge struct {
+	Cursor string  `json:"cursor"`
+	Node   *Member `json:"node"`
+}
 
 type NewEvent struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description"`
-	Adress      *string `json:"adress"`
-	Start       string  `json:"start"`
-	End         *string `json:"end"`
+	Organization string     `json:"organization"`
+	Sections     []string   `json:"sections"`
+	Name         string     `json:"name"`
+	Description  *string    `json:"description"`
+	Adress       *string    `json:"adress"`
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end"`
+	Recurrence   *string    `json:"recurrence"`
 }
 
 type NewInvite struct {
-	User    string `json:"user"`
-	Section string `json:"section"`
+	User    *string `json:"user"`
+	Email   *string `json:"email"`
+	Section string  `json:"section"`
+	Right   *int    `json:"right"`
 }
 
 type NewOrganization struct {
-	Name    string  `json:"name"`
-	Picture *string `json:"picture"`
+	Name     string  `json:"name"`
+	Picture  *string `json:"picture"`
+	Timezone *string `json:"timezone"`
 }
 
 type NewSection struct {
@@ -91,44 +133,77 @@ type NewSection struct {
 }
 
 type NewUser struct {
-	Username string  `json:"username"`
-	Password string  `json:"password"`
-	Email    string  `json:"email"`
-	Showname *string `json:"showname"`
+	Username    string  `json:"username"`
+	Password    string  `json:"password"`
+	Email       string  `json:"email"`
+	Showname    *string `json:"showname"`
+	InviteToken *string `json:"inviteToken"`
 }
 
-type Organization struct {
-	ID       string     `json:"id"`
-	Name     string     `json:"name"`
-	Sections []*Section `json:"sections"`
-	Picture  *string    `json:"picture"`
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
 }
 
-func (Organization) IsNode() {}
-
 type RefreshTokenInput struct {
 	Token string `json:"token"`
 }
 
-type Section struct {
-	ID           string        `json:"id"`
-	Name         string        `json:"name"`
-	Organization *Organization `json:"organization"`
-	Member       []*Member     `json:"member"`
+type SectionConnection struct {
+	Edges      []*SectionEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+type SectionEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Section `json:"node"`
+}
+
+type ChangeKind string
+
+const (
+	ChangeKindCreated ChangeKind = "CREATED"
+	ChangeKindUpdated ChangeKind = "UPDATED"
+	ChangeKindDeleted ChangeKind = "DELETED"
+)
+
+var AllChangeKind = []ChangeKind{
+	ChangeKindCreated,
+	ChangeKindUpdated,
+	ChangeKindDeleted,
+}
+
+func (e ChangeKind) IsValid() bool {
+	switch e {
+	case ChangeKindCreated, ChangeKindUpdated, ChangeKindDeleted:
+		return true
+	}
+	return false
 }
 
-func (Section) IsNode() {}
+func (e ChangeKind) String() string {
+	return string(e)
+}
 
-type User struct {
-	ID        string  `json:"id"`
-	Username  string  `json:"username"`
-	Password  string  `json:"password"`
-	Email     string  `json:"email"`
-	Showname  *string `json:"showname"`
-	Superuser bool    `json:"superuser"`
+func (e *ChangeKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ChangeKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ChangeKind", str)
+	}
+	return nil
 }
 
-func (User) IsNode() {}
+func (e ChangeKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
 
 type Commitment string
 
@@ -172,3 +247,185 @@ func (e *Commitment) UnmarshalGQL(v interface{}) error {
 func (e Commitment) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+type ImportStatus string
+
+const (
+	ImportStatusCreated   ImportStatus = "CREATED"
+	ImportStatusDuplicate ImportStatus = "DUPLICATE"
+	ImportStatusInvalid   ImportStatus = "INVALID"
+)
+
+var AllImportStatus = []ImportStatus{
+	ImportStatusCreated,
+	ImportStatusDuplicate,
+	ImportStatusInvalid,
+}
+
+func (e ImportStatus) IsValid() bool {
+	switch e {
+	case ImportStatusCreated, ImportStatusDuplicate, ImportStatusInvalid:
+		return true
+	}
+	return false
+}
+
+func (e ImportStatus) String() string {
+	return string(e)
+}
+
+func (e *ImportStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ImportStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ImportStatus", str)
+	}
+	return nil
+}
+
+func (e ImportStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RecurrenceRange string
+
+const (
+	RecurrenceRangeThis      RecurrenceRange = "THIS"
+	RecurrenceRangeFollowing RecurrenceRange = "FOLLOWING"
+	RecurrenceRangeAll       RecurrenceRange = "ALL"
+)
+
+var AllRecurrenceRange = []RecurrenceRange{
+	RecurrenceRangeThis,
+	RecurrenceRangeFollowing,
+	RecurrenceRangeAll,
+}
+
+func (e RecurrenceRange) IsValid() bool {
+	switch e {
+	case RecurrenceRangeThis, RecurrenceRangeFollowing, RecurrenceRangeAll:
+		return true
+	}
+	return false
+}
+
+func (e RecurrenceRange) String() string {
+	return string(e)
+}
+
+func (e *RecurrenceRange) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RecurrenceRange(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RecurrenceRange", str)
+	}
+	return nil
+}
+
+func (e RecurrenceRange) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Right string
+
+const (
+	RightView          Right = "VIEW"
+	RightComment       Right = "COMMENT"
+	RightManageEvents  Right = "MANAGE_EVENTS"
+	RightManageMembers Right = "MANAGE_MEMBERS"
+	RightAdmin         Right = "ADMIN"
+)
+
+var AllRight = []Right{
+	RightView,
+	RightComment,
+	RightManageEvents,
+	RightManageMembers,
+	RightAdmin,
+}
+
+func (e Right) IsValid() bool {
+	switch e {
+	case RightView, RightComment, RightManageEvents, RightManageMembers, RightAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Right) String() string {
+	return string(e)
+}
+
+func (e *Right) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Right(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Right", str)
+	}
+	return nil
+}
+
+func (e Right) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RightScope string
+
+const (
+	RightScopeSection           RightScope = "SECTION"
+	RightScopeOrganization      RightScope = "ORGANIZATION"
+	RightScopeWholeOrganization RightScope = "WHOLE_ORGANIZATION"
+	RightScopeEvent             RightScope = "EVENT"
+	RightScopeComment           RightScope = "COMMENT"
+	RightScopeInvite            RightScope = "INVITE"
+)
+
+var AllRightScope = []RightScope{
+	RightScopeSection,
+	RightScopeOrganization,
+	RightScopeWholeOrganization,
+	RightScopeEvent,
+	RightScopeComment,
+	RightScopeInvite,
+}
+
+func (e RightScope) IsValid() bool {
+	switch e {
+	case RightScopeSection, RightScopeOrganization, RightScopeWholeOrganization, RightScopeEvent, RightScopeComment, RightScopeInvite:
+		return true
+	}
+	return false
+}
+
+func (e RightScope) String() string {
+	return string(e)
+}
+
+func (e *RightScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RightScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RightScope", str)
+	}
+	return nil
+}
+
+func (e RightScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
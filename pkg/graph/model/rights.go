@@ -0,0 +1,27 @@
+package model
+
+// Bit returns the bit r occupies in Member.Right.
+func (r Right) Bit() int {
+	for i, v := range AllRight {
+		if v == r {
+			return 1 << i
+		}
+	}
+	return 0
+}
+
+// Has reports whether the member holds r. Admins hold every right.
+func (m *Member) Has(r Right) bool {
+	return m.Right&RightAdmin.Bit() != 0 || m.Right&r.Bit() != 0
+}
+
+// Rights decodes Member.Right.
+func (m *Member) Rights() []Right {
+	rights := []Right{}
+	for _, r := range AllRight {
+		if m.Right&r.Bit() != 0 {
+			rights = append(rights, r)
+		}
+	}
+	return rights
+}
@@ -0,0 +1,212 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// access is what the caller of a query may see. Superusers see
+// everything, other users what concerns them and what belongs to the
+// sections they hold the VIEW right in:
+//
+//   - users who are members of such a section,
+//   - the members of such a section,
+//   - events for such a section, their comments and their attendees,
+//   - invites to sections they hold MANAGE_MEMBERS in.
+//
+// Events without an organization are seen by their creators and by the
+// users who responded to them.
+type access struct {
+	user *model.User
+	// rights holds the caller's rights by section.
+	rights map[string]int
+	// orgs holds the organizations of the sections the caller holds the
+	// VIEW right in.
+	orgs map[string]bool
+}
+
+// access looks up what the caller may see, failing for anonymous callers.
+func (r *Resolver) access(ctx context.Context) (*access, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.accessOf(ctx, u)
+}
+
+// accessOf looks up what u may see.
+func (r *Resolver) accessOf(ctx context.Context, u *model.User) (*access, error) {
+	a := &access{user: u, rights: map[string]int{}, orgs: map[string]bool{}}
+	if u.Superuser {
+		return a, nil
+	}
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &u.ID})
+	if err != nil {
+		return nil, err
+	}
+	var viewable []string
+	for _, m := range members {
+		a.rights[m.SectionID] = m.Right
+		if m.Has(model.RightView) {
+			viewable = append(viewable, m.SectionID)
+		}
+	}
+	sections, err := r.sections(ctx, viewable)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sections {
+		a.orgs[s.OrganizationID] = true
+	}
+	return a, nil
+}
+
+// holds reports whether the caller holds right in any of the sections.
+func (a *access) holds(right model.Right, sections ...string) bool {
+	if a.user.Superuser {
+		return true
+	}
+	for _, id := range sections {
+		m := model.Member{Right: a.rights[id]}
+		if m.Has(right) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) canViewUser(ctx context.Context, a *access, u *model.User) (bool, error) {
+	if a.user.Superuser || u.ID == a.user.ID {
+		return true, nil
+	}
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &u.ID})
+	if err != nil {
+		return false, err
+	}
+	for _, m := range members {
+		if a.holds(model.RightView, m.SectionID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *access) canViewMember(m *model.Member) bool {
+	return m.UserID == a.user.ID || a.holds(model.RightView, m.SectionID)
+}
+
+func (r *Resolver) canViewEvent(ctx context.Context, a *access, e *model.Event) (bool, error) {
+	switch {
+	case a.user.Superuser || e.CreatorID == a.user.ID:
+		return true, nil
+	case len(e.SectionIDs) > 0:
+		return a.holds(model.RightView, e.SectionIDs...), nil
+	case e.OrganizationID != nil:
+		// The event is for every section of its organization.
+		return a.orgs[*e.OrganizationID], nil
+	}
+	id, at, err := responseTarget(e)
+	if err != nil {
+		// Recurring events are responded to by occurrence.
+		id, at = e.ID, nil
+	}
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{
+		EventID:    &id,
+		Occurrence: at,
+		UserID:     &a.user.ID,
+	})
+	return len(attendees) > 0, err
+}
+
+func (r *Resolver) canViewComment(ctx context.Context, a *access, c *model.Comment) (bool, error) {
+	e, err := r.event(ctx, c.EventID)
+	if err != nil {
+		return false, err
+	}
+	return r.canViewEvent(ctx, a, e)
+}
+
+func (r *Resolver) canViewAttendee(ctx context.Context, a *access, at *model.Attendee) (bool, error) {
+	if at.UserID == a.user.ID {
+		return true, nil
+	}
+	e, err := r.attendeeEvent(ctx, at)
+	if err != nil {
+		return false, err
+	}
+	return r.canViewEvent(ctx, a, e)
+}
+
+func (a *access) canViewInvite(i *model.Invite) bool {
+	return (i.UserID != nil && *i.UserID == a.user.ID) ||
+		(i.InvitedByID != nil && *i.InvitedByID == a.user.ID) ||
+		a.holds(model.RightManageMembers, i.SectionID)
+}
+
+// errNoView is returned for objects the caller may not see.
+var errNoView = fmt.Errorf("%w: requires the %s right", ErrForbidden, model.RightView)
+
+// requireVisible returns an error unless the caller may see n.
+// Organizations and sections are seen by everyone.
+func (r *Resolver) requireVisible(ctx context.Context, n model.Node) error {
+	switch n.(type) {
+	case *model.Organization, *model.Section:
+		return nil
+	}
+	a, err := r.access(ctx)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	switch n := n.(type) {
+	case *model.User:
+		ok, err = r.canViewUser(ctx, a, n)
+	case *model.Member:
+		ok = a.canViewMember(n)
+	case *model.Event:
+		ok, err = r.canViewEvent(ctx, a, n)
+	case *model.Comment:
+		ok, err = r.canViewComment(ctx, a, n)
+	case *model.Attendee:
+		ok, err = r.canViewAttendee(ctx, a, n)
+	case *model.Invite:
+		ok = a.canViewInvite(n)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNoView
+	}
+	return nil
+}
+
+// The functions below return the items of lists the caller may see.
+
+func (a *access) members(members []*model.Member) []*model.Member {
+	visible := make([]*model.Member, 0, len(members))
+	for _, m := range members {
+		if a.canViewMember(m) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
+
+func (r *Resolver) visibleEvents(ctx context.Context, a *access, events []*model.Event) ([]*model.Event, error) {
+	visible := make([]*model.Event, 0, len(events))
+	for _, e := range events {
+		ok, err := r.canViewEvent(ctx, a, e)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, e)
+		}
+	}
+	return visible, nil
+}
@@ -0,0 +1,225 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/ical"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// CalendarPath is the prefix the iCalendar feeds are served under. The
+// path continues with the calendar token of the user and one of
+//
+//	/me.ics
+//	/organization/<id>.ics
+//	/section/<id>.ics
+const CalendarPath = "/calendar/"
+
+// Feeds hold the events starting within calendarPast before and
+// calendarHorizon after the time they are fetched.
+const (
+	calendarPast    = 90 * 24 * time.Hour
+	calendarHorizon = 366 * 24 * time.Hour
+)
+
+// commitmentLabels are appended to the summary of events the user has
+// responded to.
+var commitmentLabels = map[model.Commitment]string{
+	model.CommitmentYes:   "attending",
+	model.CommitmentMaybe: "maybe",
+	model.CommitmentNo:    "declined",
+}
+
+// createCalendarToken issues a new calendar token for the user, revoking
+// the previous one.
+func (r *Resolver) createCalendarToken(ctx context.Context, u *model.User) (string, error) {
+	token, err := auth.RandomToken()
+	if err != nil {
+		return "", err
+	}
+	hash := auth.HashToken(token)
+	u.CalendarTokenHash = &hash
+	if err := r.Store.Users.Update(ctx, u); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CalendarHandler serves the iCalendar feeds below CalendarPath. As
+// calendar applications cannot send access tokens, feeds are authorized
+// by the calendar token in their path.
+func (r *Resolver) CalendarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		path := strings.Split(strings.TrimPrefix(req.URL.Path, CalendarPath), "/")
+		// The token is kept out of the log.
+		feed := strings.Join(path[1:], "/")
+		cal, err := r.calendar(req.Context(), path, time.Now())
+		switch {
+		case errors.Is(err, storage.ErrNotFound), errors.Is(err, relay.ErrInvalidID):
+			http.NotFound(w, req)
+			return
+		case errors.Is(err, ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("serving calendar %s: %v", feed, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ical.ContentType)
+		if err := ical.Write(w, cal); err != nil {
+			log.Printf("writing calendar %s: %v", feed, err)
+		}
+	})
+}
+
+// calendar builds the feed at the given path below CalendarPath. Unknown
+// paths and tokens yield storage.ErrNotFound.
+func (r *Resolver) calendar(ctx context.Context, path []string, now time.Time) (*ical.Calendar, error) {
+	if len(path) < 2 || path[0] == "" {
+		return nil, storage.ErrNotFound
+	}
+	u, err := r.Store.Users.GetByCalendarTokenHash(ctx, auth.HashToken(path[0]))
+	if err != nil {
+		return nil, err
+	}
+	from, to := now.Add(-calendarPast), now.Add(calendarHorizon)
+
+	switch {
+	case len(path) == 2 && path[1] == "me.ics":
+		events, err := r.personalEvents(ctx, u.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		return r.calendarOf(ctx, u, u.Username, events, now)
+	case len(path) == 3 && path[1] == "organization" && strings.HasSuffix(path[2], ".ics"):
+		id, err := relay.Decode(strings.TrimSuffix(path[2], ".ics"), "Organization")
+		if err != nil {
+			return nil, err
+		}
+		o, err := r.Store.Organizations.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sections, err := r.Store.Sections.ListByOrganization(ctx, o.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(sections))
+		for i, s := range sections {
+			ids[i] = s.ID
+		}
+		if err := r.requireView(ctx, u, ids); err != nil {
+			return nil, err
+		}
+		events, err := r.listEvents(ctx, storage.EventFilter{OrganizationID: &o.ID, Start: &from, End: &to})
+		if err != nil {
+			return nil, err
+		}
+		a, err := r.accessOf(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		if events, err = r.visibleEvents(ctx, a, events); err != nil {
+			return nil, err
+		}
+		return r.calendarOf(ctx, u, o.Name, events, now)
+	case len(path) == 3 && path[1] == "section" && strings.HasSuffix(path[2], ".ics"):
+		id, err := relay.Decode(strings.TrimSuffix(path[2], ".ics"), "Section")
+		if err != nil {
+			return nil, err
+		}
+		s, err := r.Store.Sections.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.requireView(ctx, u, []string{s.ID}); err != nil {
+			return nil, err
+		}
+		o, err := r.Store.Organizations.Get(ctx, s.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		all, err := r.listEvents(ctx, storage.EventFilter{OrganizationID: &o.ID, Start: &from, End: &to})
+		if err != nil {
+			return nil, err
+		}
+		var events []*model.Event
+		for _, e := range all {
+			if expected(e, map[string]bool{s.ID: true}) {
+				events = append(events, e)
+			}
+		}
+		return r.calendarOf(ctx, u, o.Name+" "+s.Name, events, now)
+	default:
+		return nil, storage.ErrNotFound
+	}
+}
+
+// requireView returns ErrForbidden unless u may view the given sections.
+func (r *Resolver) requireView(ctx context.Context, u *model.User, sections []string) error {
+	if u.Superuser {
+		return nil
+	}
+	ok, err := r.holdsRight(ctx, u.ID, model.RightView, sections)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: requires the %s right", ErrForbidden, model.RightView)
+	}
+	return nil
+}
+
+// calendarOf renders events for u, noting u's response in the summary.
+// Declined events are not reminded of.
+func (r *Resolver) calendarOf(ctx context.Context, u *model.User, name string, events []*model.Event, now time.Time) (*ical.Calendar, error) {
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{UserID: &u.ID})
+	if err != nil {
+		return nil, err
+	}
+	commitments := make(map[string]model.Commitment, len(attendees))
+	for _, a := range attendees {
+		commitments[attendeeKey(a)] = a.Commitment
+	}
+
+	cal := &ical.Calendar{Name: name, Events: make([]ical.Event, len(events))}
+	for i, e := range events {
+		ie := ical.Event{
+			UID:      e.Key(),
+			Stamp:    now,
+			Start:    e.Start,
+			End:      e.End,
+			Summary:  e.Name,
+			Reminder: r.CalendarReminder,
+		}
+		if e.Description != nil {
+			ie.Description = *e.Description
+		}
+		if e.Adress != nil {
+			ie.Location = *e.Adress
+		}
+		if c, ok := commitments[e.Key()]; ok {
+			ie.Summary += " (" + commitmentLabels[c] + ")"
+			if c == model.CommitmentNo {
+				ie.Reminder = 0
+			}
+		}
+		cal.Events[i] = ie
+	}
+	return cal, nil
+}
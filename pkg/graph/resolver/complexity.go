@@ -0,0 +1,106 @@
+package resolver
+
+import (
+	"math"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/generated"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+// Complexity estimates the cost of list fields as that of their items
+// times the number of items: the page size of connections and the number
+// of IDs of nodes, or else listSize.
+func Complexity(listSize int) generated.ComplexityRoot {
+	list := func(childComplexity int) int {
+		return items(listSize, childComplexity)
+	}
+	connection := func(childComplexity int, first *int, last *int) int {
+		n := listSize
+		if first != nil {
+			n = *first
+		}
+		if last != nil && (first == nil || *last < n) {
+			n = *last
+		}
+		return items(n, childComplexity)
+	}
+
+	var c generated.ComplexityRoot
+	c.Organization.Sections = func(childComplexity int, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Section.Member = func(childComplexity int, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Event.Sections = list
+	c.Event.ExpectedAttendees = list
+	c.Event.Comments = func(childComplexity int, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Event.Attendees = func(childComplexity int, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Event.Occurrences = func(childComplexity int, start time.Time, end time.Time) int {
+		return items(expansion(&start, &end), childComplexity)
+	}
+	c.Viewer.Memberships = list
+	c.Viewer.Organizations = list
+	c.Viewer.Invites = list
+	c.Viewer.UpcomingEvents = list
+	c.ImportedEvent.Conflicts = list
+	c.ImportResult.Events = list
+	c.Query.Nodes = func(childComplexity int, ids []string) int {
+		return items(len(ids), childComplexity)
+	}
+	c.Query.Members = func(childComplexity int, section *string, user *string, right *int, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Query.Events = func(childComplexity int, organization *string, start *time.Time, end *time.Time, first *int, after *string, last *int, before *string) int {
+		// Expanding recurring events costs a step per day of the bounds.
+		return connection(childComplexity, first, last) + expansion(start, end)
+	}
+	c.Query.Comments = func(childComplexity int, event string, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Query.Attendees = func(childComplexity int, event *string, user *string, commitment *model.Commitment, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	c.Query.Invites = func(childComplexity int, section *string, user *string, first *int, after *string, last *int, before *string) int {
+		return connection(childComplexity, first, last)
+	}
+	return c
+}
+
+// expansion returns the number of days recurring events are expanded over
+// within the given bounds, which is the most occurrences a series can
+// have in them. Bounds rejected by checkExpansion count as the longest
+// allowed.
+func expansion(start, end *time.Time) int {
+	if end == nil {
+		return 0
+	}
+	max := int(maxExpansion/(24*time.Hour)) + 1
+	if start == nil || end.Sub(*start) > maxExpansion {
+		return max
+	}
+	if end.Before(*start) {
+		return 1
+	}
+	return int(end.Sub(*start)/(24*time.Hour)) + 1
+}
+
+// maxComplexity caps estimates, keeping those of absurdly large operations
+// from overflowing.
+const maxComplexity = math.MaxInt32
+
+// items returns the complexity of a list of n items.
+func items(n, childComplexity int) int {
+	if n <= 0 {
+		return 1
+	}
+	if childComplexity > (maxComplexity-1)/n {
+		return maxComplexity
+	}
+	return 1 + n*childComplexity
+}
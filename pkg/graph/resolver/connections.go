@@ -0,0 +1,353 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// Connections page through lists by the keys of their items, see
+// storage.Page. Lists are either read from the store a page at a time or,
+// if the resolver has them at hand anyway, sliced in memory.
+
+func page(first *int, after *string, last *int, before *string) relay.Page {
+	return relay.Page{First: first, After: after, Last: last, Before: before}
+}
+
+// item is an element of a list along with its key.
+type item struct {
+	key  storage.Key
+	node interface{}
+}
+
+// list is a list connections page through.
+type list interface {
+	// fetch returns the items of the list within p, in list order.
+	fetch(ctx context.Context, p storage.Page) ([]item, error)
+}
+
+// sliceList is a list held in memory, sorted by key.
+type sliceList []item
+
+func (l sliceList) fetch(ctx context.Context, p storage.Page) ([]item, error) {
+	if len(l) > 0 && (p.After != nil && len(p.After) != len(l[0].key) || p.Before != nil && len(p.Before) != len(l[0].key)) {
+		return nil, storage.ErrInvalidKey
+	}
+	lo, hi := 0, len(l)
+	if p.After != nil {
+		lo = sort.Search(len(l), func(i int) bool { return p.After.Less(l[i].key) })
+	}
+	if p.Before != nil {
+		hi = sort.Search(len(l), func(i int) bool { return !l[i].key.Less(p.Before) })
+	}
+	if hi < lo {
+		hi = lo
+	}
+	if p.Limit > 0 && hi-lo > p.Limit {
+		if p.Last {
+			lo = hi - p.Limit
+		} else {
+			hi = lo + p.Limit
+		}
+	}
+	return l[lo:hi], nil
+}
+
+// storeList is a list read from the store by read. If visible is set, the
+// items it rejects are left out, and pages short of them are filled up
+// with the items following in the store. count, if set, counts the items
+// of lists without visible, which are read in full to count them
+// otherwise.
+type storeList struct {
+	read    func(ctx context.Context, p storage.Page) ([]item, error)
+	visible func(ctx context.Context, node interface{}) (bool, error)
+	count   func(ctx context.Context) (int, error)
+}
+
+// readSize is the fewest items read at once for lists leaving out some.
+const readSize = 100
+
+func (l storeList) fetch(ctx context.Context, p storage.Page) ([]item, error) {
+	if l.visible == nil {
+		return l.read(ctx, p)
+	}
+	want := p.Limit
+	if p.Limit > 0 && p.Limit < readSize {
+		p.Limit = readSize
+	}
+	var items []item
+	for {
+		read, err := l.read(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		var kept []item
+		for _, it := range read {
+			ok, err := l.visible(ctx, it.node)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				kept = append(kept, it)
+			}
+		}
+		if p.Last {
+			items = append(kept, items...)
+		} else {
+			items = append(items, kept...)
+		}
+		if p.Limit == 0 || len(read) < p.Limit || len(items) >= want {
+			break
+		}
+		if p.Last {
+			p.Before = read[0].key
+		} else {
+			p.After = read[len(read)-1].key
+		}
+	}
+	if want > 0 && len(items) > want {
+		if p.Last {
+			items = items[len(items)-want:]
+		} else {
+			items = items[:want]
+		}
+	}
+	return items, nil
+}
+
+// window is the part of a list selected by the arguments of a
+// connection.
+type window struct {
+	items    []item
+	pageInfo *model.PageInfo
+	// totalCount is only counted if the connection asks for it.
+	totalCount int
+}
+
+// paginate selects the window of l given by pg. It reads one item beyond
+// the window to tell whether there are more, and looks for items on the
+// other side of the window only if a cursor is given there.
+func paginate(ctx context.Context, l list, pg relay.Page) (*window, error) {
+	if pg.First != nil && *pg.First < 0 {
+		return nil, apperr.Field("first", "must not be negative")
+	}
+	if pg.Last != nil && *pg.Last < 0 {
+		return nil, apperr.Field("last", "must not be negative")
+	}
+	var (
+		p   storage.Page
+		err error
+	)
+	if pg.After != nil {
+		if p.After, err = relay.ParseCursor(*pg.After); err != nil {
+			return nil, err
+		}
+	}
+	if pg.Before != nil {
+		if p.Before, err = relay.ParseCursor(*pg.Before); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case pg.First != nil:
+		p.Limit = *pg.First + 1
+	case pg.Last != nil:
+		p.Limit, p.Last = *pg.Last+1, true
+	}
+	items, err := l.fetch(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var hasNext, hasPrevious bool
+	if pg.First != nil && len(items) > *pg.First {
+		items, hasNext = items[:*pg.First], true
+	}
+	if pg.Last != nil && len(items) > *pg.Last {
+		items, hasPrevious = items[len(items)-*pg.Last:], true
+	}
+	if !hasNext && p.Before != nil {
+		q := storage.Page{After: p.After, Limit: 1}
+		if len(items) > 0 {
+			q.After = items[len(items)-1].key
+		}
+		if hasNext, err = exists(ctx, l, q); err != nil {
+			return nil, err
+		}
+	}
+	if !hasPrevious && p.After != nil {
+		q := storage.Page{Before: p.Before, Limit: 1, Last: true}
+		if len(items) > 0 {
+			q.Before = items[0].key
+		}
+		if hasPrevious, err = exists(ctx, l, q); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &window{
+		items:    items,
+		pageInfo: &model.PageInfo{HasNextPage: hasNext, HasPreviousPage: hasPrevious},
+	}
+	if len(items) > 0 {
+		start, end := relay.Cursor(items[0].key), relay.Cursor(items[len(items)-1].key)
+		w.pageInfo.StartCursor, w.pageInfo.EndCursor = &start, &end
+	}
+	for _, f := range graphql.CollectAllFields(ctx) {
+		if f == "totalCount" {
+			if w.totalCount, err = count(ctx, l); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return w, nil
+}
+
+// count returns the number of items of l.
+func count(ctx context.Context, l list) (int, error) {
+	if l, ok := l.(storeList); ok && l.count != nil && l.visible == nil {
+		return l.count(ctx)
+	}
+	all, err := l.fetch(ctx, storage.Page{})
+	return len(all), err
+}
+
+func exists(ctx context.Context, l list, p storage.Page) (bool, error) {
+	items, err := l.fetch(ctx, p)
+	return len(items) > 0, err
+}
+
+// The functions below page through a list and wrap the items selected
+// into the edges of a connection.
+
+func sectionConnection(ctx context.Context, l list, pg relay.Page) (*model.SectionConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.SectionConnection{Edges: make([]*model.SectionEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.SectionEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Section)}
+	}
+	return c, nil
+}
+
+func memberConnection(ctx context.Context, l list, pg relay.Page) (*model.MemberConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.MemberConnection{Edges: make([]*model.MemberEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.MemberEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Member)}
+	}
+	return c, nil
+}
+
+func eventConnection(ctx context.Context, l list, pg relay.Page) (*model.EventConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.EventConnection{Edges: make([]*model.EventEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.EventEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Event)}
+	}
+	return c, nil
+}
+
+func commentConnection(ctx context.Context, l list, pg relay.Page) (*model.CommentConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.CommentConnection{Edges: make([]*model.CommentEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.CommentEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Comment)}
+	}
+	return c, nil
+}
+
+func attendeeConnection(ctx context.Context, l list, pg relay.Page) (*model.AttendeeConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.AttendeeConnection{Edges: make([]*model.AttendeeEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.AttendeeEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Attendee)}
+	}
+	return c, nil
+}
+
+func inviteConnection(ctx context.Context, l list, pg relay.Page) (*model.InviteConnection, error) {
+	w, err := paginate(ctx, l, pg)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.InviteConnection{Edges: make([]*model.InviteEdge, len(w.items)), PageInfo: w.pageInfo, TotalCount: w.totalCount}
+	for i, it := range w.items {
+		c.Edges[i] = &model.InviteEdge{Cursor: relay.Cursor(it.key), Node: it.node.(*model.Invite)}
+	}
+	return c, nil
+}
+
+// commentList is the list of the comments of the event with the given ID.
+func (r *Resolver) commentList(eventID string) storeList {
+	return storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			comments, err := r.Store.Comments.ListByEvent(ctx, eventID, &p)
+			return itemsOf(comments), err
+		},
+		count: func(ctx context.Context) (int, error) {
+			return r.Store.Comments.CountByEvent(ctx, eventID)
+		},
+	}
+}
+
+// itemsOf pairs the entities of a list with their keys, sorted by key.
+func itemsOf(list interface{}) []item {
+	var items []item
+	switch list := list.(type) {
+	case []*model.Section:
+		for _, s := range list {
+			items = append(items, item{storage.SectionKey(s), s})
+		}
+	case []*model.Member:
+		for _, m := range list {
+			items = append(items, item{storage.MemberKey(m), m})
+		}
+	case []*model.Event:
+		for _, e := range list {
+			items = append(items, item{eventKey(e), e})
+		}
+	case []*model.Comment:
+		for _, c := range list {
+			items = append(items, item{storage.CommentKey(c), c})
+		}
+	case []*model.Attendee:
+		for _, a := range list {
+			items = append(items, item{storage.AttendeeKey(a), a})
+		}
+	case []*model.Invite:
+		for _, i := range list {
+			items = append(items, item{storage.InviteKey(i), i})
+		}
+	default:
+		panic(fmt.Sprintf("no keys for %T", list))
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].key.Less(items[j].key) })
+	return items
+}
+
+// eventKey is the key of events, which are listed by start. Occurrences
+// of a recurring event share its ID, but not their keys.
+func eventKey(e *model.Event) storage.Key {
+	return storage.Key{storage.KeyTime(e.Start), e.Key()}
+}
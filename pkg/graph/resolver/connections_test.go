@@ -0,0 +1,236 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// connectionContext returns the context of a connection field selecting
+// the given subfields.
+func connectionContext(fields ...string) context.Context {
+	var sel ast.SelectionSet
+	for _, f := range fields {
+		sel = append(sel, &ast.Field{Name: f, Alias: f})
+	}
+	ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{})
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Field: graphql.CollectedField{Field: &ast.Field{SelectionSet: sel}, Selections: sel},
+	})
+}
+
+// pairs holds ten items keyed by a name shared by pairs of items.
+var pairs = sliceList{
+	{key: storage.Key{"000", "000"}, node: "n0"},
+	{key: storage.Key{"000", "001"}, node: "n1"},
+	{key: storage.Key{"001", "002"}, node: "n2"},
+	{key: storage.Key{"001", "003"}, node: "n3"},
+	{key: storage.Key{"002", "004"}, node: "n4"},
+	{key: storage.Key{"002", "005"}, node: "n5"},
+	{key: storage.Key{"003", "006"}, node: "n6"},
+	{key: storage.Key{"003", "007"}, node: "n7"},
+	{key: storage.Key{"004", "008"}, node: "n8"},
+	{key: storage.Key{"004", "009"}, node: "n9"},
+}
+
+func nodes(items []item) []string {
+	var s []string
+	for _, it := range items {
+		s = append(s, it.node.(string))
+	}
+	return s
+}
+
+func TestPaginate(t *testing.T) {
+	l := pairs
+	cursor := func(i int) *string {
+		c := relay.Cursor(l[i].key)
+		return &c
+	}
+	// Cursors of items removed from the list, right after n4 and before
+	// n0.
+	removed := relay.Cursor([]string{"002", "004a"})
+	beforeAll := relay.Cursor([]string{"", ""})
+	n := func(i int) *int { return &i }
+
+	tests := []struct {
+		name                 string
+		pg                   relay.Page
+		want                 []string
+		hasNext, hasPrevious bool
+	}{
+		{"all", relay.Page{}, nodes(l), false, false},
+		{"first", relay.Page{First: n(3)}, []string{"n0", "n1", "n2"}, true, false},
+		{"first all", relay.Page{First: n(10)}, nodes(l), false, false},
+		{"first more", relay.Page{First: n(20)}, nodes(l), false, false},
+		{"first none", relay.Page{First: n(0)}, nil, true, false},
+		{"first after", relay.Page{First: n(3), After: cursor(2)}, []string{"n3", "n4", "n5"}, true, true},
+		{"first after to the end", relay.Page{First: n(3), After: cursor(6)}, []string{"n7", "n8", "n9"}, false, true},
+		{"after", relay.Page{After: cursor(7)}, []string{"n8", "n9"}, false, true},
+		{"after the last", relay.Page{After: cursor(9)}, nil, false, true},
+		{"last", relay.Page{Last: n(3)}, []string{"n7", "n8", "n9"}, false, true},
+		{"last all", relay.Page{Last: n(10)}, nodes(l), false, false},
+		{"last none", relay.Page{Last: n(0)}, nil, false, true},
+		{"last before", relay.Page{Last: n(3), Before: cursor(7)}, []string{"n4", "n5", "n6"}, true, true},
+		{"before", relay.Page{Before: cursor(2)}, []string{"n0", "n1"}, true, false},
+		{"before the first", relay.Page{Before: cursor(0)}, nil, true, false},
+		{"between", relay.Page{After: cursor(2), Before: cursor(6)}, []string{"n3", "n4", "n5"}, true, true},
+		{"first between", relay.Page{First: n(2), After: cursor(2), Before: cursor(6)}, []string{"n3", "n4"}, true, true},
+		{"last between", relay.Page{Last: n(2), After: cursor(2), Before: cursor(6)}, []string{"n4", "n5"}, true, true},
+		{"between adjacent", relay.Page{After: cursor(4), Before: cursor(5)}, nil, true, true},
+		{"after a removed item", relay.Page{First: n(2), After: &removed}, []string{"n5", "n6"}, true, true},
+		{"before a removed item", relay.Page{Last: n(2), Before: &removed}, []string{"n3", "n4"}, true, true},
+		{"after a position before all", relay.Page{First: n(2), After: &beforeAll}, []string{"n0", "n1"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := paginate(connectionContext("edges", "pageInfo"), l, tt.pg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodes(w.items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %q, want %q", got, tt.want)
+			}
+			info := w.pageInfo
+			if info.HasNextPage != tt.hasNext || info.HasPreviousPage != tt.hasPrevious {
+				t.Errorf("hasNextPage, hasPreviousPage = %v, %v, want %v, %v", info.HasNextPage, info.HasPreviousPage, tt.hasNext, tt.hasPrevious)
+			}
+			if len(w.items) == 0 {
+				if info.StartCursor != nil || info.EndCursor != nil {
+					t.Error("cursors set on an empty page")
+				}
+				return
+			}
+			if *info.StartCursor != relay.Cursor(w.items[0].key) || *info.EndCursor != relay.Cursor(w.items[len(w.items)-1].key) {
+				t.Error("cursors are not those of the first and last item")
+			}
+			if w.totalCount != 0 {
+				t.Errorf("totalCount = %d without being selected", w.totalCount)
+			}
+		})
+	}
+}
+
+func TestPaginateTotalCount(t *testing.T) {
+	n := 2
+	w, err := paginate(connectionContext("totalCount"), pairs, relay.Page{First: &n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.totalCount != 10 {
+		t.Errorf("totalCount = %d, want 10", w.totalCount)
+	}
+
+	reads := 0
+	counted := storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			reads++
+			return pairs.fetch(ctx, p)
+		},
+		count: func(ctx context.Context) (int, error) { return len(pairs), nil },
+	}
+	if w, err = paginate(connectionContext("totalCount"), counted, relay.Page{First: &n}); err != nil {
+		t.Fatal(err)
+	}
+	if w.totalCount != 10 || reads != 1 {
+		t.Errorf("totalCount = %d after %d reads, want 10 after 1", w.totalCount, reads)
+	}
+}
+
+func TestPaginateInvalid(t *testing.T) {
+	negative := -1
+	malformed := "!!!"
+	short := relay.Cursor([]string{"000"})
+	tests := []struct {
+		name string
+		pg   relay.Page
+		// field is the argument a Validation error is about, if any.
+		field string
+		// is is the error returned, if it is a sentinel.
+		is error
+	}{
+		{"negative first", relay.Page{First: &negative}, "first", nil},
+		{"negative last", relay.Page{Last: &negative}, "last", nil},
+		{"malformed after", relay.Page{After: &malformed}, "", relay.ErrInvalidCursor},
+		{"malformed before", relay.Page{Before: &malformed}, "", relay.ErrInvalidCursor},
+		{"key of another list", relay.Page{After: &short}, "", storage.ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		_, err := paginate(connectionContext("edges"), pairs, tt.pg)
+		if apperr.CodeOf(err) != apperr.Validation {
+			t.Errorf("%s: paginate = %v, want a validation error", tt.name, err)
+			continue
+		}
+		var e *apperr.Error
+		if tt.field != "" && (!errors.As(err, &e) || len(e.Fields) != 1 || e.Fields[0].Field != tt.field) {
+			t.Errorf("%s: paginate = %v, want an error about %s", tt.name, err, tt.field)
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s: paginate = %v, want %v", tt.name, err, tt.is)
+		}
+	}
+}
+
+// TestStoreListVisible checks that lists leaving out items page like the
+// list of the items they keep, across the reads needed to fill a page.
+func TestStoreListVisible(t *testing.T) {
+	all := make(sliceList, 3*readSize)
+	for i := range all {
+		all[i] = item{key: storage.Key{fmt.Sprintf("%03d", i)}, node: fmt.Sprintf("n%d", i)}
+	}
+	var kept sliceList
+	for i, it := range all {
+		if i%3 != 1 {
+			kept = append(kept, it)
+		}
+	}
+	reads := 0
+	l := storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			reads++
+			return all.fetch(ctx, p)
+		},
+		visible: func(ctx context.Context, node interface{}) (bool, error) {
+			var i int
+			fmt.Sscanf(node.(string), "n%d", &i)
+			return i%3 != 1, nil
+		},
+	}
+
+	for _, size := range []int{0, 1, readSize - 1, readSize, readSize + 1, 2 * readSize} {
+		for _, at := range []int{0, 3, readSize, 2*readSize + 1, len(all) - 1} {
+			c := relay.Cursor(all[at].key)
+			size := size
+			for _, pg := range []relay.Page{
+				{First: &size},
+				{Last: &size},
+				{First: &size, After: &c},
+				{Last: &size, Before: &c},
+			} {
+				reads = 0
+				got, err := paginate(connectionContext("edges"), l, pg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := paginate(connectionContext("edges"), kept, pg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(nodes(got.items), nodes(want.items)) || !reflect.DeepEqual(got.pageInfo, want.pageInfo) {
+					t.Errorf("size %d at n%d: page %q, %+v, want %q, %+v", size, at, nodes(got.items), *got.pageInfo, nodes(want.items), *want.pageInfo)
+				}
+				if reads > 6 {
+					t.Errorf("size %d at n%d: %d reads", size, at, reads)
+				}
+			}
+		}
+	}
+}
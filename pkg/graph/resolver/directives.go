@@ -0,0 +1,302 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/generated"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// ErrForbidden is returned when the caller lacks the right a field
+// requires.
+var ErrForbidden = apperr.New(apperr.Forbidden, "forbidden")
+
+// Directives returns the implementations of the schema directives.
+func (r *Resolver) Directives() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		HasRight: r.hasRight,
+	}
+}
+
+func (r *Resolver) hasRight(ctx context.Context, obj interface{}, next graphql.Resolver, right model.Right, scope model.RightScope, arg string, self *string) (interface{}, error) {
+	if err := r.authorize(ctx, right, scope, arg, self); err != nil {
+		return nil, err
+	}
+	if err := validateArgs(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+// authorize implements @hasRight.
+func (r *Resolver) authorize(ctx context.Context, right model.Right, scope model.RightScope, arg string, self *string) error {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return err
+	}
+	if u.Superuser {
+		return nil
+	}
+
+	args := rawArgs(ctx)
+	if self != nil {
+		if id, err := relay.Decode(lookupArg(args, *self), "User"); err == nil && id == u.ID {
+			return nil
+		}
+	}
+	gid := lookupArg(args, arg)
+	if gid == "" {
+		return apperr.Field(arg, "must be set")
+	}
+	var id string
+	if scope == model.RightScopeEvent {
+		// Rights on an occurrence are those on its series.
+		id, _, err = decodeEvent(gid)
+	} else {
+		id, err = relay.Decode(gid, scopeTypes[scope])
+	}
+	if err != nil {
+		return err
+	}
+
+	sections, owner, err := r.scopeSections(ctx, scope, id)
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner == u.ID {
+		return nil
+	}
+	hold := r.holdsRight
+	if scope == model.RightScopeWholeOrganization {
+		hold = r.holdsRightInAll
+	}
+	ok, err := hold(ctx, u.ID, right, sections)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: requires the %s right", ErrForbidden, right)
+	}
+	return nil
+}
+
+// rawArgs returns the arguments of the current field as sent by the
+// client, with input objects still as maps.
+func rawArgs(ctx context.Context) map[string]interface{} {
+	fc := graphql.GetFieldContext(ctx)
+	oc := graphql.GetOperationContext(ctx)
+	return fc.Field.ArgumentMap(oc.Variables)
+}
+
+// scopeTypes maps each scope to the type of the IDs it is looked up by.
+var scopeTypes = map[model.RightScope]string{
+	model.RightScopeSection:           "Section",
+	model.RightScopeOrganization:      "Organization",
+	model.RightScopeWholeOrganization: "Organization",
+	model.RightScopeEvent:             "Event",
+	model.RightScopeComment:           "Comment",
+	model.RightScopeInvite:            "Invite",
+}
+
+// lookupArg follows a dotted path through args and returns the ID found at
+// its end, or "" if there is none.
+func lookupArg(args map[string]interface{}, path string) string {
+	var v interface{} = args
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	id, _ := v.(string)
+	return id
+}
+
+// scopeSections returns the sections a right is checked in for the entity
+// with the given ID, and the user owning the entity, if any.
+func (r *Resolver) scopeSections(ctx context.Context, scope model.RightScope, id string) ([]string, string, error) {
+	switch scope {
+	case model.RightScopeSection:
+		return []string{id}, "", nil
+	case model.RightScopeOrganization, model.RightScopeWholeOrganization:
+		sections, err := r.Store.Sections.ListByOrganization(ctx, id, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		ids := make([]string, len(sections))
+		for i, s := range sections {
+			ids[i] = s.ID
+		}
+		return ids, "", nil
+	case model.RightScopeEvent:
+		e, err := r.Store.Events.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		sections, err := r.eventSections(ctx, e)
+		return sections, e.CreatorID, err
+	case model.RightScopeComment:
+		c, err := r.Store.Comments.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := r.Store.Events.Get(ctx, c.EventID)
+		if err != nil {
+			return nil, "", err
+		}
+		sections, err := r.eventSections(ctx, e)
+		return sections, c.CreatorID, err
+	case model.RightScopeInvite:
+		i, err := r.Store.Invites.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		var owner string
+		if i.UserID != nil {
+			owner = *i.UserID
+		}
+		return []string{i.SectionID}, owner, nil
+	}
+	return nil, "", fmt.Errorf("unknown right scope %s", scope)
+}
+
+// eventSections returns the sections an event is for: the ones it names,
+// or else every section of its organization. Events without an
+// organization are for no section, so only their creators and superusers
+// may manage them.
+func (r *Resolver) eventSections(ctx context.Context, e *model.Event) ([]string, error) {
+	if len(e.SectionIDs) > 0 || e.OrganizationID == nil {
+		return e.SectionIDs, nil
+	}
+	sections, err := r.Store.Sections.ListByOrganization(ctx, *e.OrganizationID, nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(sections))
+	for i, s := range sections {
+		ids[i] = s.ID
+	}
+	return ids, nil
+}
+
+// holdsRight reports whether the user holds right in any of the sections.
+func (r *Resolver) holdsRight(ctx context.Context, userID string, right model.Right, sections []string) (bool, error) {
+	if len(sections) == 0 {
+		return false, nil
+	}
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &userID})
+	if err != nil {
+		return false, err
+	}
+	in := map[string]bool{}
+	for _, s := range sections {
+		in[s] = true
+	}
+	for _, m := range members {
+		if in[m.SectionID] && m.Has(right) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// holdsRightInAll reports whether the user holds right in every one of
+// the sections, of which there must be at least one.
+func (r *Resolver) holdsRightInAll(ctx context.Context, userID string, right model.Right, sections []string) (bool, error) {
+	if len(sections) == 0 {
+		return false, nil
+	}
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &userID})
+	if err != nil {
+		return false, err
+	}
+	held := map[string]bool{}
+	for _, m := range members {
+		held[m.SectionID] = m.Has(right)
+	}
+	for _, s := range sections {
+		if !held[s] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkAttendee rejects responses to e from users who are not members
+// of any section the event is for. Events without an organization accept
+// responses from everyone.
+func (r *Resolver) checkAttendee(ctx context.Context, e *model.Event, userID string) error {
+	if e.OrganizationID == nil {
+		return nil
+	}
+	sections, err := r.eventSections(ctx, e)
+	if err != nil {
+		return err
+	}
+	ok, err := r.holdsRight(ctx, userID, model.RightView, sections)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: the user is not a member of the event's sections", ErrForbidden)
+	}
+	return nil
+}
+
+// checkGrant makes sure the caller holds every right they hand out or take
+// away in the section, so that managing members can be used neither to
+// escalate rights nor to demote or remove members holding more rights
+// than the caller. Changing a member touches the rights they hold and
+// the ones they get, removing them the rights they hold.
+func (r *Resolver) checkGrant(ctx context.Context, sectionID string, right int) error {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return err
+	}
+	if u.Superuser {
+		return nil
+	}
+	m, err := r.Store.Members.GetBySectionUser(ctx, sectionID, u.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if m.Has(model.RightAdmin) || right&^m.Right == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: cannot grant or revoke rights you do not hold", ErrForbidden)
+}
+
+// requireSelf allows superusers and the user with the given ID.
+func requireSelf(ctx context.Context, userID string) error {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return err
+	}
+	if !u.Superuser && u.ID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func requireSuperuser(ctx context.Context) error {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return err
+	}
+	if !u.Superuser {
+		return fmt.Errorf("%w: superuser required", ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/generated"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/pubsub"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// fixture is the data testServer starts with: the superuser root, alice
+// holding ADMIN in both sections of the orchestra, strings and winds, bob
+// holding VIEW in strings, carol holding ADMIN in winds, and an event of
+// the orchestra bob created.
+type fixture struct {
+	root, alice, bob, carol *model.User
+	// Global IDs.
+	orchestra, strings, event string
+}
+
+// testServer runs operations against a schema over a memory store.
+type testServer struct {
+	*fixture
+	h *handler.Server
+	// errs holds the errors of the last operation as the resolvers
+	// returned them.
+	errs []error
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	f := &fixture{
+		root:  &model.User{Username: "root", Email: "root@example.org", Superuser: true},
+		alice: &model.User{Username: "alice", Email: "alice@example.org"},
+		bob:   &model.User{Username: "bob", Email: "bob@example.org"},
+		carol: &model.User{Username: "carol", Email: "carol@example.org"},
+	}
+	for _, u := range []*model.User{f.root, f.alice, f.bob, f.carol} {
+		if err := store.Users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	org := &model.Organization{Name: "Orchestra", Timezone: "UTC"}
+	if err := store.Organizations.Create(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	section := &model.Section{Name: "Strings", OrganizationID: org.ID}
+	if err := store.Sections.Create(ctx, section); err != nil {
+		t.Fatal(err)
+	}
+	winds := &model.Section{Name: "Winds", OrganizationID: org.ID}
+	if err := store.Sections.Create(ctx, winds); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*model.Member{
+		{UserID: f.alice.ID, SectionID: section.ID, Right: model.RightAdmin.Bit()},
+		{UserID: f.alice.ID, SectionID: winds.ID, Right: model.RightAdmin.Bit()},
+		{UserID: f.bob.ID, SectionID: section.ID, Right: model.RightView.Bit()},
+		{UserID: f.carol.ID, SectionID: winds.ID, Right: model.RightAdmin.Bit()},
+	} {
+		if err := store.Members.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := &model.Event{
+		Name:           "Rehearsal",
+		Start:          time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC),
+		CreatorID:      f.bob.ID,
+		OrganizationID: &org.ID,
+	}
+	if err := store.Events.Create(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	f.orchestra = relay.ToGlobalID("Organization", org.ID)
+	f.strings = relay.ToGlobalID("Section", section.ID)
+	f.event = relay.ToGlobalID("Event", e.ID)
+
+	r := &Resolver{Store: store, Bus: pubsub.New()}
+	s := &testServer{fixture: f}
+	s.h = handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  r,
+		Directives: r.Directives(),
+	}))
+	s.h.AddTransport(transport.POST{})
+	s.h.Use(r.Validation())
+	s.h.SetErrorPresenter(func(ctx context.Context, err error) *gqlerror.Error {
+		s.errs = append(s.errs, err)
+		return graphql.DefaultErrorPresenter(ctx, err)
+	})
+	return s
+}
+
+// do runs query as u, anonymously if u is nil, and returns the first
+// error of the operation.
+func (s *testServer) do(t *testing.T, u *model.User, query string, vars map[string]interface{}) error {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/query", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if u != nil {
+		req = req.WithContext(auth.WithUser(req.Context(), u))
+	}
+	s.errs = nil
+	s.h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return s.errs[0]
+}
+
+func TestHasRight(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  func(f *fixture) *model.User
+		query string
+		vars  func(f *fixture) map[string]interface{}
+		want  apperr.Code
+	}{
+		{
+			"anonymous",
+			func(f *fixture) *model.User { return nil },
+			`mutation($id: ID!) { updateSection(id: $id, name: "Violins") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.strings} },
+			apperr.Unauthenticated,
+		},
+		{
+			"superuser",
+			func(f *fixture) *model.User { return f.root },
+			`mutation($id: ID!) { updateSection(id: $id, name: "Violins") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.strings} },
+			"",
+		},
+		{
+			"right held in the section",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($id: ID!) { updateSection(id: $id, name: "Violins") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.strings} },
+			"",
+		},
+		{
+			"right lacking in the section",
+			func(f *fixture) *model.User { return f.bob },
+			`mutation($id: ID!) { updateSection(id: $id, name: "Violins") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.strings} },
+			apperr.Forbidden,
+		},
+		{
+			"ID of another type",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($id: ID!) { updateSection(id: $id, name: "Violins") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra} },
+			apperr.Validation,
+		},
+		{
+			"path into an input object",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($org: ID!) { createSection(section: {name: "Winds", organization: $org}) { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"org": f.orchestra} },
+			"",
+		},
+		{
+			"path into an input object without the right",
+			func(f *fixture) *model.User { return f.bob },
+			`mutation($org: ID!) { createSection(section: {name: "Winds", organization: $org}) { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"org": f.orchestra} },
+			apperr.Forbidden,
+		},
+		{
+			"right held in the whole organization",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($id: ID!) { updateOrganization(id: $id, name: "Philharmonic") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra} },
+			"",
+		},
+		{
+			"right held in one section of the organization",
+			func(f *fixture) *model.User { return f.carol },
+			`mutation($id: ID!) { updateOrganization(id: $id, name: "Philharmonic") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra} },
+			apperr.Forbidden,
+		},
+		{
+			"delete with the right held in one section of the organization",
+			func(f *fixture) *model.User { return f.carol },
+			`mutation($id: ID!) { deleteOrganization(id: $id) { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra} },
+			apperr.Forbidden,
+		},
+		{
+			"creator of the event",
+			func(f *fixture) *model.User { return f.bob },
+			`mutation($id: ID!) { updateEvent(id: $id, name: "Tutti") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.event} },
+			"",
+		},
+		{
+			"right held in the event's organization",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($id: ID!) { updateEvent(id: $id, name: "Tutti") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.event} },
+			"",
+		},
+		{
+			"self",
+			func(f *fixture) *model.User { return f.bob },
+			`mutation($section: ID!, $user: ID!) { deleteSectionMember(section: $section, user: $user) { right } }`,
+			func(f *fixture) map[string]interface{} {
+				return map[string]interface{}{"section": f.strings, "user": relay.ToGlobalID("User", f.bob.ID)}
+			},
+			"",
+		},
+		{
+			"someone else",
+			func(f *fixture) *model.User { return f.bob },
+			`mutation($section: ID!, $user: ID!) { deleteSectionMember(section: $section, user: $user) { right } }`,
+			func(f *fixture) map[string]interface{} {
+				return map[string]interface{}{"section": f.strings, "user": relay.ToGlobalID("User", f.alice.ID)}
+			},
+			apperr.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			err := s.do(t, tt.user(s.fixture), tt.query, tt.vars(s.fixture))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("error %v, want none", err)
+				}
+				return
+			}
+			if got := apperr.CodeOf(err); got != tt.want {
+				t.Errorf("error %v with code %s, want %s", err, got, tt.want)
+			}
+		})
+	}
+}
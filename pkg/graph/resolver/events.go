@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"context"
+	"sort"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+)
+
+// organizationSections decodes the section IDs an event is to be for and
+// makes sure they are distinct sections of the organization. The result
+// is ordered by ID, like the store returns it.
+func (r *Resolver) organizationSections(ctx context.Context, organizationID string, gids []string) ([]string, error) {
+	seen := map[string]bool{}
+	ids := make([]string, 0, len(gids))
+	for _, gid := range gids {
+		id, err := relay.Decode(gid, "Section")
+		if err != nil {
+			return nil, err
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		s, err := r.Store.Sections.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if s.OrganizationID != organizationID {
+			return nil, apperr.Errorf(apperr.Validation, "section %s belongs to another organization", gid)
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// expectedAttendees returns the users who are members of a section the
+// event is for, ordered by username.
+func (r *Resolver) expectedAttendees(ctx context.Context, e *model.Event) ([]*model.User, error) {
+	sections, err := r.eventSections(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var ids []string
+	for _, sectionID := range sections {
+		members, err := r.sectionMembers(ctx, sectionID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if !seen[m.UserID] {
+				seen[m.UserID] = true
+				ids = append(ids, m.UserID)
+			}
+		}
+	}
+	users, err := r.users(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
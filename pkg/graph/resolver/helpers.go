@@ -0,0 +1,32 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// ignoreNotFound drops storage.ErrNotFound so that lookups through
+// nullable query fields resolve to null instead of an error.
+func ignoreNotFound(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *Resolver) authPayload(ctx context.Context, t *auth.Tokens) (*model.AuthPayload, error) {
+	u, err := r.Store.Users.Get(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthPayload{
+		AccessToken:  t.AccessToken,
+		ExpiresIn:    int(t.ExpiresIn.Seconds()),
+		RefreshToken: t.RefreshToken,
+		User:         u,
+	}, nil
+}
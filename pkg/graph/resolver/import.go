@@ -0,0 +1,488 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/ical"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// importHorizon bounds how far past their start the occurrences of
+// imported recurring events are checked for conflicts.
+const importHorizon = 366 * 24 * time.Hour
+
+// importRange bounds how far from now imported events may start, and so
+// the span the recurring events of the organization are expanded over to
+// find conflicts.
+const importRange = 2 * 366 * 24 * time.Hour
+
+// ImportCalendar creates events in the organization from the VEVENTs of
+// the iCalendar data read from file, for the sections with the given
+// global IDs or, if there are none, for the whole organization. Events
+// whose UID has been imported into the organization before, and events
+// that cannot be imported, are skipped. The events are created all at
+// once after the calendar has been read; with dryRun nothing is created.
+func (r *Resolver) ImportCalendar(ctx context.Context, creatorID, organizationID string, sections []string, file io.Reader, dryRun bool) (*model.ImportResult, error) {
+	o, err := r.Store.Organizations.Get(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(o.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	sectionIDs, err := r.organizationSections(ctx, o.ID, sections)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := ical.Parse(file, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &importer{
+		Resolver:   r,
+		creatorID:  creatorID,
+		org:        o,
+		loc:        loc,
+		sectionIDs: sectionIDs,
+		dryRun:     dryRun,
+		now:        time.Now(),
+		series:     map[string]*model.Event{},
+		imported:   map[string]*model.Event{},
+		replaced:   map[string]bool{},
+		superseded: map[string]bool{},
+	}
+	if err := imp.loadExisting(ctx, cal.Events); err != nil {
+		return nil, err
+	}
+	for _, ie := range cal.Events {
+		if ie.RecurrenceID != nil {
+			imp.superseded[occurrenceKey(ie.UID, *ie.RecurrenceID)] = true
+		}
+	}
+
+	// Events replacing occurrences are imported after the recurring events
+	// they belong to, which may come later in the calendar.
+	result := &model.ImportResult{DryRun: dryRun, Events: make([]*model.ImportedEvent, len(cal.Events))}
+	for _, replacing := range []bool{false, true} {
+		for i := range cal.Events {
+			ie := &cal.Events[i]
+			if (ie.RecurrenceID != nil) != replacing {
+				continue
+			}
+			imp.index = i
+			if replacing {
+				result.Events[i], err = imp.importOccurrence(ctx, ie)
+			} else {
+				result.Events[i], err = imp.importEvent(ctx, ie)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if result.Events[i].Status == model.ImportStatusCreated {
+				result.Created++
+			}
+		}
+	}
+	for _, ev := range result.Events {
+		sort.Ints(ev.CalendarConflicts)
+	}
+	if err := imp.save(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importer holds the state of one ImportCalendar call.
+type importer struct {
+	*Resolver
+	creatorID  string
+	org        *model.Organization
+	loc        *time.Location
+	sectionIDs []string
+	dryRun     bool
+	now        time.Time
+	// index is that of the event of the calendar being imported.
+	index int
+	// existing holds the events and occurrences of the organization up to
+	// the end of the calendar, to find conflicts in.
+	existing []*model.Event
+	// spans holds the events and occurrences imported so far, to find
+	// conflicts among the events of the calendar.
+	spans []span
+	// series holds the recurring events created by UID, imported holds
+	// those imported before.
+	series   map[string]*model.Event
+	imported map[string]*model.Event
+	// replaced records the occurrences replaced so far by UID and start,
+	// superseded those the calendar replaces or cancels anywhere, which
+	// are not checked for conflicts.
+	replaced   map[string]bool
+	superseded map[string]bool
+	// created holds the reports on the events to create, events those
+	// events, in order.
+	created []*model.ImportedEvent
+	events  []*model.Event
+}
+
+// span is an event or occurrence imported from the calendar, along with
+// the report on the event and its index in the calendar.
+type span struct {
+	*model.Event
+	ev    *model.ImportedEvent
+	index int
+}
+
+func occurrenceKey(uid string, at time.Time) string {
+	return uid + "@" + at.UTC().Format(model.RecurrenceIDFormat)
+}
+
+// inRange reports whether t is within importRange of now.
+func (imp *importer) inRange(t time.Time) bool {
+	return !t.Before(imp.now.Add(-importRange)) && !t.After(imp.now.Add(importRange))
+}
+
+// loadExisting loads the events imported events may conflict with: the
+// single events starting before the horizon of the last imported event
+// ends, and the occurrences of recurring events from the first imported
+// event on. Events out of range are left out, as they are not imported.
+func (imp *importer) loadExisting(ctx context.Context, events []ical.Event) error {
+	var from, to time.Time
+	for _, ie := range events {
+		if ie.Start.IsZero() || !imp.inRange(ie.Start) {
+			continue
+		}
+		if from.IsZero() || ie.Start.Before(from) {
+			from = ie.Start
+		}
+		if ie.Start.After(to) {
+			to = ie.Start
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+	to = to.Add(importHorizon)
+
+	stored, err := imp.Store.Events.List(ctx, storage.EventFilter{OrganizationID: &imp.org.ID, End: &to})
+	if err != nil {
+		return err
+	}
+	replaced, err := imp.replacementsOf(ctx, stored)
+	if err != nil {
+		return err
+	}
+	for _, e := range stored {
+		if e.Recurrence == nil {
+			imp.existing = append(imp.existing, e)
+			continue
+		}
+		// The span may be longer than clients may have events expanded
+		// over, so it is expanded a part at a time.
+		for start := from; !start.After(to); {
+			end := start.Add(maxExpansion)
+			if end.After(to) {
+				end = to
+			}
+			if err := checkExpansion("start", &start, "end", end); err != nil {
+				return err
+			}
+			occurrences, err := imp.expand(ctx, e, replaced[e.ID], start, end)
+			if err != nil {
+				return err
+			}
+			imp.existing = append(imp.existing, occurrences...)
+			start = end.Add(time.Nanosecond)
+		}
+	}
+	sortEvents(imp.existing)
+	return nil
+}
+
+// importEvent imports a single or recurring event.
+func (imp *importer) importEvent(ctx context.Context, ie *ical.Event) (*model.ImportedEvent, error) {
+	ev := imp.report(ie)
+	e, msg, err := imp.newEvent(ie)
+	if err != nil {
+		return nil, err
+	}
+	if msg != "" {
+		return invalid(ev, msg), nil
+	}
+
+	if ie.UID != "" {
+		if imp.series[ie.UID] != nil || imp.imported[ie.UID] != nil {
+			return duplicate(ev, nil, "the UID occurs earlier in the calendar"), nil
+		}
+		existing, err := imp.Store.Events.List(ctx, storage.EventFilter{OrganizationID: &imp.org.ID, UID: &ie.UID})
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			imp.imported[ie.UID] = existing[0]
+			return duplicate(ev, existing[0], "the event has been imported before"), nil
+		}
+		uid := ie.UID
+		e.UID = &uid
+	}
+
+	if err := imp.create(ctx, ev, e); err != nil {
+		return nil, err
+	}
+	if ie.UID != "" {
+		imp.series[ie.UID] = e
+	}
+	return ev, nil
+}
+
+// importOccurrence imports an event replacing an occurrence of a
+// recurring event, or removing it if cancelled.
+func (imp *importer) importOccurrence(ctx context.Context, ie *ical.Event) (*model.ImportedEvent, error) {
+	ev := imp.report(ie)
+	series := imp.series[ie.UID]
+	switch {
+	case ie.Invalid != "":
+		return invalid(ev, ie.Invalid), nil
+	case series == nil && imp.imported[ie.UID] != nil:
+		return duplicate(ev, imp.imported[ie.UID], "its recurring event has been imported before"), nil
+	case series == nil || series.Recurrence == nil:
+		return invalid(ev, "the event replaces an occurrence of no recurring event imported from the calendar"), nil
+	}
+
+	at := ie.RecurrenceID.UTC()
+	times, err := imp.seriesTimes(ctx, series, at, at)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 || excluded(series, at) {
+		return invalid(ev, "RECURRENCE-ID is no occurrence of its recurring event"), nil
+	}
+	key := occurrenceKey(ie.UID, at)
+	if imp.replaced[key] {
+		return duplicate(ev, nil, "the occurrence is replaced earlier in the calendar"), nil
+	}
+
+	if ie.Cancelled {
+		imp.replaced[key] = true
+		// The recurring event is created with the occurrence excluded.
+		before, after := splitTimes(series.ExDates, at)
+		series.ExDates = append(append(before, at), after...)
+		msg := "the occurrence is removed from its recurring event"
+		ev.Status, ev.Message = model.ImportStatusCreated, &msg
+		return ev, nil
+	}
+	if ie.Recurrence != "" {
+		return invalid(ev, "an event replacing an occurrence cannot recur"), nil
+	}
+	e, msg, err := imp.newEvent(ie)
+	if err != nil {
+		return nil, err
+	}
+	if msg != "" {
+		return invalid(ev, msg), nil
+	}
+	// The ID of the series is set once it is created, before e.
+	e.SeriesID, e.RecurrenceID = &series.ID, &at
+
+	if err := imp.create(ctx, ev, e); err != nil {
+		return nil, err
+	}
+	imp.replaced[key] = true
+	return ev, nil
+}
+
+// report starts the report on ie.
+func (imp *importer) report(ie *ical.Event) *model.ImportedEvent {
+	ev := &model.ImportedEvent{Conflicts: []*model.Event{}, CalendarConflicts: []int{}}
+	if uid := ie.UID; uid != "" {
+		ev.UID = &uid
+	}
+	if name := ie.Summary; name != "" {
+		ev.Name = &name
+	}
+	if !ie.Start.IsZero() {
+		start := ie.Start.In(imp.loc)
+		ev.Start = &start
+	}
+	return ev
+}
+
+// newEvent converts ie into an event of the organization, or returns why
+// it cannot be imported.
+func (imp *importer) newEvent(ie *ical.Event) (*model.Event, string, error) {
+	switch {
+	case ie.Invalid != "":
+		return nil, ie.Invalid, nil
+	case ie.Start.IsZero():
+		return nil, "the event has no start", nil
+	case ie.Summary == "":
+		return nil, "the event has no summary", nil
+	case !imp.inRange(ie.Start):
+		return nil, fmt.Sprintf("the event starts more than %d days from now", importRange/(24*time.Hour)), nil
+	case !endsAfterStart(ie.Start, ie.End):
+		return nil, "the event does not end after it starts", nil
+	case ie.Cancelled:
+		return nil, "the event is cancelled", nil
+	}
+	recurrence, err := parseRecurrence(ie.Recurrence)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+
+	e := &model.Event{
+		Name:           ie.Summary,
+		Start:          ie.Start.UTC(),
+		CreatorID:      imp.creatorID,
+		OrganizationID: &imp.org.ID,
+		SectionIDs:     append([]string(nil), imp.sectionIDs...),
+		Recurrence:     recurrence,
+	}
+	if description := ie.Description; description != "" {
+		e.Description = &description
+	}
+	if location := ie.Location; location != "" {
+		e.Adress = &location
+	}
+	if ie.End != nil {
+		end := ie.End.UTC()
+		e.End = &end
+	}
+	if recurrence != nil {
+		for _, t := range ie.ExDates {
+			if t = t.UTC(); !excluded(e, t) {
+				before, after := splitTimes(e.ExDates, t)
+				e.ExDates = append(append(before, t), after...)
+			}
+		}
+	}
+	return e, "", nil
+}
+
+// create reports the conflicts of e and records it to be created.
+func (imp *importer) create(ctx context.Context, ev *model.ImportedEvent, e *model.Event) error {
+	spans := []span{{Event: e, ev: ev, index: imp.index}}
+	if e.Recurrence != nil {
+		times, err := imp.seriesTimes(ctx, e, e.Start, e.Start.Add(importHorizon))
+		if err != nil {
+			return err
+		}
+		spans = spans[:0]
+		for _, t := range times {
+			if !excluded(e, t) && (e.UID == nil || !imp.superseded[occurrenceKey(*e.UID, t)]) {
+				spans = append(spans, span{Event: occurrence(e, t), ev: ev, index: imp.index})
+			}
+		}
+	}
+
+	for _, x := range imp.existing {
+		if !shareSections(x.SectionIDs, e.SectionIDs) {
+			continue
+		}
+		for _, s := range spans {
+			if overlaps(s.Event, x) {
+				ev.Conflicts = append(ev.Conflicts, x)
+				break
+			}
+		}
+	}
+	for _, x := range imp.spans {
+		if !shareSections(x.SectionIDs, e.SectionIDs) {
+			continue
+		}
+		for _, s := range spans {
+			if overlaps(s.Event, x.Event) {
+				ev.CalendarConflicts = addIndex(ev.CalendarConflicts, x.index)
+				x.ev.CalendarConflicts = addIndex(x.ev.CalendarConflicts, imp.index)
+				break
+			}
+		}
+	}
+	imp.spans = append(imp.spans, spans...)
+
+	ev.Status = model.ImportStatusCreated
+	imp.created = append(imp.created, ev)
+	imp.events = append(imp.events, e)
+	return nil
+}
+
+func addIndex(indexes []int, i int) []int {
+	for _, j := range indexes {
+		if j == i {
+			return indexes
+		}
+	}
+	return append(indexes, i)
+}
+
+// save creates the events recorded by create in one transaction, unless
+// on a dry run.
+func (imp *importer) save(ctx context.Context) error {
+	if imp.dryRun {
+		return nil
+	}
+	err := imp.Store.Atomic(ctx, func(s *storage.Store) error {
+		for _, e := range imp.events {
+			if err := s.Events.Create(ctx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, ev := range imp.created {
+		ev.Event = imp.events[i]
+	}
+	return nil
+}
+
+func invalid(ev *model.ImportedEvent, msg string) *model.ImportedEvent {
+	ev.Status = model.ImportStatusInvalid
+	ev.Message = &msg
+	return ev
+}
+
+func duplicate(ev *model.ImportedEvent, of *model.Event, msg string) *model.ImportedEvent {
+	ev.Status = model.ImportStatusDuplicate
+	ev.DuplicateOf = of
+	ev.Message = &msg
+	return ev
+}
+
+// overlaps reports whether a and b take place at the same time. Events
+// without an end last no time.
+func overlaps(a, b *model.Event) bool {
+	return a.Start.Equal(b.Start) || a.Start.Before(eventEnd(b)) && b.Start.Before(eventEnd(a))
+}
+
+func eventEnd(e *model.Event) time.Time {
+	if e.End == nil {
+		return e.Start
+	}
+	return *e.End
+}
+
+// shareSections reports whether events for the sections a and b have
+// members in common, events for no sections being for all of them.
+func shareSections(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	in := make(map[string]bool, len(a))
+	for _, id := range a {
+		in[id] = true
+	}
+	for _, id := range b {
+		if in[id] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+)
+
+func TestImportCalendarTimes(t *testing.T) {
+	const stamp = "20060102T150405Z"
+	start := time.Now().UTC().Truncate(time.Hour).AddDate(0, 1, 0)
+	dtstart := "DTSTART:" + start.Format(stamp)
+	dtend := func(d time.Duration) string { return "DTEND:" + start.Add(d).Format(stamp) }
+	tests := []struct {
+		name  string
+		times []string
+		want  model.ImportStatus
+	}{
+		{"end after start", []string{dtstart, dtend(2 * time.Hour)}, model.ImportStatusCreated},
+		{"no end", []string{dtstart}, model.ImportStatusCreated},
+		{"zero length", []string{dtstart, dtend(0)}, model.ImportStatusInvalid},
+		{"end before start", []string{dtstart, dtend(-time.Hour)}, model.ImportStatusInvalid},
+		{"far in the future", []string{"DTSTART:99991231T180000Z"}, model.ImportStatusInvalid},
+		{"far in the past", []string{"DTSTART:" + start.AddDate(-3, 0, 0).Format(stamp)}, model.ImportStatusInvalid},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		store := memory.New()
+		u := &model.User{Username: "alice", Email: "alice@example.org"}
+		if err := store.Users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		o := &model.Organization{Name: "Orchestra", Timezone: "UTC"}
+		if err := store.Organizations.Create(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		r := &Resolver{Store: store}
+
+		cal := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1\r\nSUMMARY:Rehearsal\r\n" +
+			strings.Join(tt.times, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+		result, err := r.ImportCalendar(ctx, u.ID, o.ID, nil, strings.NewReader(cal), false)
+		if err != nil {
+			t.Fatalf("%s: ImportCalendar = %v", tt.name, err)
+		}
+		if got := result.Events[0].Status; got != tt.want {
+			t.Errorf("%s: status %s, want %s", tt.name, got, tt.want)
+		}
+		events, err := store.Events.List(ctx, storage.EventFilter{OrganizationID: &o.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created := len(events) == 1; created != (tt.want == model.ImportStatusCreated) {
+			t.Errorf("%s: %d events created", tt.name, len(events))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+var (
+	// ErrInviteExpired is returned when redeeming an invite past its
+	// expiry.
+	ErrInviteExpired = apperr.New(apperr.Forbidden, "the invite has expired")
+	// ErrInvalidInviteToken is returned for sign-ups with an invite token
+	// that belongs to no invite.
+	ErrInvalidInviteToken = apperr.New(apperr.Validation, "invalid invite token")
+)
+
+// newInvite builds the invite described by in, addressed to an existing
+// user or to an email address. The token to mail along with invites by
+// email is returned too.
+func (r *Resolver) newInvite(ctx context.Context, in model.NewInvite) (*model.Invite, string, error) {
+	sectionID, err := relay.Decode(in.Section, "Section")
+	if err != nil {
+		return nil, "", err
+	}
+	if (in.User == nil) == (in.Email == nil) {
+		return nil, "", apperr.New(apperr.Validation, "exactly one of user and email must be set")
+	}
+
+	i := &model.Invite{SectionID: sectionID}
+	if in.Right != nil {
+		i.Right = *in.Right
+	}
+	if err := r.checkGrant(ctx, sectionID, i.Right); err != nil {
+		return nil, "", err
+	}
+	if u, err := auth.RequireUser(ctx); err == nil {
+		i.InvitedByID = &u.ID
+	}
+	expires := time.Now().UTC().Add(r.InviteTTL).Truncate(time.Second)
+	i.ExpiresAt = &expires
+
+	if in.User != nil {
+		userID, err := relay.Decode(*in.User, "User")
+		if err != nil {
+			return nil, "", err
+		}
+		_, err = r.Store.Members.GetBySectionUser(ctx, sectionID, userID)
+		if err == nil {
+			return nil, "", fmt.Errorf("%w: the user is already a member of the section", storage.ErrConflict)
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, "", err
+		}
+		i.UserID = &userID
+		return i, "", nil
+	}
+
+	addr, err := mail.ParseAddress(*in.Email)
+	if err != nil {
+		return nil, "", apperr.Field("invite.email", "%q is not an email address", *in.Email)
+	}
+	token, err := auth.RandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	hash := auth.HashToken(token)
+	i.Email = &addr.Address
+	i.TokenHash = &hash
+	return i, token, nil
+}
+
+// mailInvite sends the token of an invite by email to its address.
+func (r *Resolver) mailInvite(ctx context.Context, i *model.Invite, token string) error {
+	s, err := r.Store.Sections.Get(ctx, i.SectionID)
+	if err != nil {
+		return err
+	}
+	o, err := r.Store.Organizations.Get(ctx, s.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("You are invited to join %s in %s.\n\n"+
+		"Sign up with the following invite token to accept:\n\n%s\n", s.Name, o.Name, token)
+	if i.ExpiresAt != nil {
+		body += fmt.Sprintf("\nThe invite expires on %s.\n", i.ExpiresAt.Format(time.RFC1123))
+	}
+	return r.Mailer.Send(ctx, *i.Email, "Invite to "+o.Name, body)
+}
+
+// inviteToUser returns the invite with the given ID if it is addressed to
+// the calling user.
+func (r *Resolver) inviteToUser(ctx context.Context, id string) (*model.Invite, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.Store.Invites.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if i.UserID == nil || *i.UserID != u.ID {
+		return nil, fmt.Errorf("%w: the invite is not addressed to you", ErrForbidden)
+	}
+	return i, nil
+}
+
+// redeemInvite makes the user a member of the invite's section with the
+// invite's rights and removes the invite, in s.
+func redeemInvite(ctx context.Context, s *storage.Store, i *model.Invite, userID string) (*model.Member, error) {
+	if i.Expired(time.Now()) {
+		return nil, ErrInviteExpired
+	}
+	m := &model.Member{
+		UserID:    userID,
+		SectionID: i.SectionID,
+		Right:     i.Right,
+	}
+	if err := s.Members.Create(ctx, m); err != nil {
+		return nil, err
+	}
+	if err := s.Invites.Delete(ctx, i.ID); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// pendingInvites drops the expired invites from invites.
+func pendingInvites(invites []*model.Invite, now time.Time) []*model.Invite {
+	pending := []*model.Invite{}
+	for _, i := range invites {
+		if !i.Expired(now) {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
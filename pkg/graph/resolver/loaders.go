@@ -0,0 +1,205 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/loader"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// The lookups below go through the loaders of the operation if it has
+// any, and straight to the store otherwise, as in mutations, subscriptions
+// and calendar feeds. The entities they return must not be modified.
+
+func (r *Resolver) user(ctx context.Context, id string) (*model.User, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Users.Load(ctx, id)
+	}
+	return r.Store.Users.Get(ctx, id)
+}
+
+// users returns the users with the given IDs in the same order.
+func (r *Resolver) users(ctx context.Context, ids []string) ([]*model.User, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Users.LoadAll(ctx, ids)
+	}
+	found, err := r.Store.Users.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.User, len(found))
+	for _, u := range found {
+		byID[u.ID] = u
+	}
+	users := make([]*model.User, len(ids))
+	for i, id := range ids {
+		if users[i] = byID[id]; users[i] == nil {
+			return nil, storage.ErrNotFound
+		}
+	}
+	return users, nil
+}
+
+func (r *Resolver) organization(ctx context.Context, id string) (*model.Organization, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Organizations.Load(ctx, id)
+	}
+	return r.Store.Organizations.Get(ctx, id)
+}
+
+// organizations returns the organizations with the given IDs in the same
+// order.
+func (r *Resolver) organizations(ctx context.Context, ids []string) ([]*model.Organization, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Organizations.LoadAll(ctx, ids)
+	}
+	found, err := r.Store.Organizations.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Organization, len(found))
+	for _, o := range found {
+		byID[o.ID] = o
+	}
+	organizations := make([]*model.Organization, len(ids))
+	for i, id := range ids {
+		if organizations[i] = byID[id]; organizations[i] == nil {
+			return nil, storage.ErrNotFound
+		}
+	}
+	return organizations, nil
+}
+
+func (r *Resolver) section(ctx context.Context, id string) (*model.Section, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Sections.Load(ctx, id)
+	}
+	return r.Store.Sections.Get(ctx, id)
+}
+
+// sections returns the sections with the given IDs in the same order.
+func (r *Resolver) sections(ctx context.Context, ids []string) ([]*model.Section, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Sections.LoadAll(ctx, ids)
+	}
+	found, err := r.Store.Sections.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.Section, len(found))
+	for _, s := range found {
+		byID[s.ID] = s
+	}
+	sections := make([]*model.Section, len(ids))
+	for i, id := range ids {
+		if sections[i] = byID[id]; sections[i] == nil {
+			return nil, storage.ErrNotFound
+		}
+	}
+	return sections, nil
+}
+
+func (r *Resolver) event(ctx context.Context, id string) (*model.Event, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Events.Load(ctx, id)
+	}
+	return r.Store.Events.Get(ctx, id)
+}
+
+// prefetchEvents loads the events the attendees respond to in one batch
+// ahead of loading them one at a time.
+func (r *Resolver) prefetchEvents(ctx context.Context, attendees []*model.Attendee) {
+	l, ok := loader.FromContext(ctx)
+	if !ok {
+		return
+	}
+	ids := make([]string, len(attendees))
+	for i, a := range attendees {
+		ids[i] = a.EventID
+	}
+	l.Events.Prefetch(ctx, ids)
+}
+
+// eventAttendees returns the responses to e, to the occurrence only if e
+// is one.
+func (r *Resolver) eventAttendees(ctx context.Context, e *model.Event) ([]*model.Attendee, error) {
+	l, ok := loader.FromContext(ctx)
+	if !ok {
+		f := storage.AttendeeFilter{EventID: &e.ID}
+		if e.IsOccurrence() {
+			f.Occurrence = e.RecurrenceID
+		}
+		return r.Store.Attendees.List(ctx, f)
+	}
+
+	all, err := l.Attendees.Load(ctx, e.ID)
+	if err != nil || !e.IsOccurrence() {
+		return all, err
+	}
+	var attendees []*model.Attendee
+	for _, a := range all {
+		if a.Occurrence != nil && a.Occurrence.Equal(*e.RecurrenceID) {
+			attendees = append(attendees, a)
+		}
+	}
+	return attendees, nil
+}
+
+// eventAttendeeList is the list of the responses to e the connection
+// selecting pg pages through. Unless pg takes only part of it, the whole
+// list is loaded like eventAttendees does, along with those of the other
+// events of the operation.
+func (r *Resolver) eventAttendeeList(ctx context.Context, e *model.Event, pg relay.Page) (list, error) {
+	if pg.First == nil && pg.Last == nil {
+		attendees, err := r.eventAttendees(ctx, e)
+		return sliceList(itemsOf(attendees)), err
+	}
+	f := storage.AttendeeFilter{EventID: &e.ID}
+	if e.IsOccurrence() {
+		f.Occurrence = e.RecurrenceID
+	}
+	return storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			f := f
+			f.Page = &p
+			attendees, err := r.Store.Attendees.List(ctx, f)
+			return itemsOf(attendees), err
+		},
+		count: func(ctx context.Context) (int, error) {
+			return r.Store.Attendees.Count(ctx, f)
+		},
+	}, nil
+}
+
+func (r *Resolver) sectionMembers(ctx context.Context, sectionID string) ([]*model.Member, error) {
+	if l, ok := loader.FromContext(ctx); ok {
+		return l.Members.Load(ctx, sectionID)
+	}
+	return r.Store.Members.List(ctx, storage.MemberFilter{SectionID: &sectionID})
+}
+
+// seriesReplacements returns the events replacing occurrences of each of
+// the recurring events with the given IDs, by series ID.
+func (r *Resolver) seriesReplacements(ctx context.Context, seriesIDs []string) (map[string][]*model.Event, error) {
+	replaced := make(map[string][]*model.Event, len(seriesIDs))
+	if l, ok := loader.FromContext(ctx); ok {
+		lists, err := l.Replacements.LoadAll(ctx, seriesIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range seriesIDs {
+			replaced[id] = lists[i]
+		}
+		return replaced, nil
+	}
+	events, err := r.Store.Events.List(ctx, storage.EventFilter{SeriesIDs: seriesIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		replaced[*e.SeriesID] = append(replaced[*e.SeriesID], e)
+	}
+	return replaced, nil
+}
@@ -0,0 +1,84 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+)
+
+// node fetches the object with the given global ID if the caller may see
+// it.
+func (r *Resolver) node(ctx context.Context, gid string) (model.Node, error) {
+	n, err := r.lookupNode(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireVisible(ctx, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// lookupNode fetches the object with the given global ID. Each case
+// returns explicitly so that a missing object never becomes a non-nil
+// interface holding a nil pointer.
+func (r *Resolver) lookupNode(ctx context.Context, gid string) (model.Node, error) {
+	typ, id, err := relay.FromGlobalID(gid)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case "User":
+		u, err := r.Store.Users.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return u, nil
+	case "Organization":
+		o, err := r.Store.Organizations.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return o, nil
+	case "Section":
+		s, err := r.Store.Sections.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "Member":
+		m, err := r.Store.Members.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	case "Event":
+		e, err := r.loadEvent(ctx, gid)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	case "Comment":
+		c, err := r.Store.Comments.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case "Attendee":
+		a, err := r.Store.Attendees.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return a, nil
+	case "Invite":
+		i, err := r.Store.Invites.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return i, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %s", relay.ErrInvalidID, typ)
+	}
+}
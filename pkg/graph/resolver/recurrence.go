@@ -0,0 +1,346 @@
+package resolver
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/rrule"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// Recurring events are stored once, together with their recurrence rule
+// and the starts of removed occurrences. Occurrences are expanded from
+// them on demand and identified by the ID of their series and their
+// original start, see model.Event.Key. An occurrence changed on its own is
+// replaced by a stored event naming the series and that start.
+
+// maxExpansion is the longest span clients may have recurring events
+// expanded over at once. As occurrences are at most a day apart, it bounds
+// the occurrences of each series in a response.
+const maxExpansion = 366 * 24 * time.Hour
+
+// checkExpansion rejects bounds too far apart to expand recurring events
+// over, naming the arguments holding them in the error.
+func checkExpansion(startArg string, start *time.Time, endArg string, end time.Time) error {
+	if start == nil {
+		return apperr.Field(startArg, "must be given along with %s", endArg)
+	}
+	if end.Sub(*start) > maxExpansion {
+		return apperr.Field(endArg, "must be at most %d days after %s", maxExpansion/(24*time.Hour), startArg)
+	}
+	return nil
+}
+
+// decodeEvent decodes the global ID of an event or of an occurrence of a
+// recurring event, for which the start of the occurrence is returned too.
+func decodeEvent(gid string) (string, *time.Time, error) {
+	key, err := relay.Decode(gid, "Event")
+	if err != nil {
+		return "", nil, err
+	}
+	i := strings.IndexByte(key, '@')
+	if i < 0 {
+		return key, nil, nil
+	}
+	at, err := time.Parse(model.RecurrenceIDFormat, key[i+1:])
+	if err != nil {
+		return "", nil, relay.ErrInvalidID
+	}
+	return key[:i], &at, nil
+}
+
+// loadEvent returns the event or occurrence with the given global ID.
+// Occurrences replaced by an event of their own resolve to that event.
+func (r *Resolver) loadEvent(ctx context.Context, gid string) (*model.Event, error) {
+	id, at, err := decodeEvent(gid)
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.Store.Events.Get(ctx, id)
+	if err != nil || at == nil {
+		return e, err
+	}
+	return r.occurrenceAt(ctx, e, *at)
+}
+
+// parseRecurrence checks a recurrence rule given by a client and returns
+// it in canonical form, or nil for the empty rule of single events.
+func parseRecurrence(s string) (*string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	rule, err := rrule.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	canonical := rule.String()
+	return &canonical, nil
+}
+
+// occurrence returns the occurrence of the recurring event e starting at
+// at.
+func occurrence(e *model.Event, at time.Time) *model.Event {
+	o := *e
+	o.SectionIDs = append([]string(nil), e.SectionIDs...)
+	o.ExDates = append([]time.Time(nil), e.ExDates...)
+	o.Start = at
+	if e.End != nil {
+		end := at.Add(e.End.Sub(e.Start))
+		o.End = &end
+	}
+	recurrenceID := at
+	o.RecurrenceID = &recurrenceID
+	return &o
+}
+
+// seriesTimes returns the starts of the occurrences of the recurring
+// event e within [from, to] as its rule gives them, including removed and
+// replaced occurrences. The rule is applied in the time zone of e.
+func (r *Resolver) seriesTimes(ctx context.Context, e *model.Event, from, to time.Time) ([]time.Time, error) {
+	rule, err := rrule.Parse(*e.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := r.eventLocation(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	times := rule.Between(e.Start.In(loc), from, to)
+	for i, t := range times {
+		times[i] = t.UTC()
+	}
+	return times, nil
+}
+
+// replacements returns the events replacing occurrences of the recurring
+// event with the given ID, by the Unix time of the occurrence they
+// replace.
+func (r *Resolver) replacements(ctx context.Context, seriesID string) (map[int64]*model.Event, error) {
+	lists, err := r.seriesReplacements(ctx, []string{seriesID})
+	if err != nil {
+		return nil, err
+	}
+	return byOccurrence(lists[seriesID]), nil
+}
+
+// byOccurrence indexes replacing events by the Unix time of the
+// occurrence they replace.
+func byOccurrence(events []*model.Event) map[int64]*model.Event {
+	replaced := make(map[int64]*model.Event, len(events))
+	for _, e := range events {
+		replaced[e.RecurrenceID.Unix()] = e
+	}
+	return replaced
+}
+
+// replacementsOf returns the replacements of the recurring events among
+// events, by series ID, looked up all at once.
+func (r *Resolver) replacementsOf(ctx context.Context, events []*model.Event) (map[string]map[int64]*model.Event, error) {
+	var ids []string
+	for _, e := range events {
+		if e.Recurrence != nil {
+			ids = append(ids, e.ID)
+		}
+	}
+	if ids == nil {
+		return nil, nil
+	}
+	lists, err := r.seriesReplacements(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replaced := make(map[string]map[int64]*model.Event, len(ids))
+	for _, id := range ids {
+		replaced[id] = byOccurrence(lists[id])
+	}
+	return replaced, nil
+}
+
+// excluded reports whether the occurrence of e starting at t has been
+// removed.
+func excluded(e *model.Event, t time.Time) bool {
+	i := sort.Search(len(e.ExDates), func(i int) bool { return !e.ExDates[i].Before(t) })
+	return i < len(e.ExDates) && e.ExDates[i].Equal(t)
+}
+
+// expand returns the occurrences of the recurring event e starting within
+// [from, to] that have been neither removed nor replaced, given the
+// replacements of e.
+func (r *Resolver) expand(ctx context.Context, e *model.Event, replaced map[int64]*model.Event, from, to time.Time) ([]*model.Event, error) {
+	times, err := r.seriesTimes(ctx, e, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var occurrences []*model.Event
+	for _, t := range times {
+		if excluded(e, t) || replaced[t.Unix()] != nil {
+			continue
+		}
+		occurrences = append(occurrences, occurrence(e, t))
+	}
+	return occurrences, nil
+}
+
+// occurrenceAt returns the occurrence of the recurring event e starting
+// at at, or the event replacing it.
+func (r *Resolver) occurrenceAt(ctx context.Context, e *model.Event, at time.Time) (*model.Event, error) {
+	if e.Recurrence == nil || excluded(e, at) {
+		return nil, storage.ErrNotFound
+	}
+	times, err := r.seriesTimes(ctx, e, at, at)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	replaced, err := r.replacements(ctx, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	if o := replaced[at.Unix()]; o != nil {
+		return o, nil
+	}
+	return occurrence(e, at), nil
+}
+
+// occurrences returns what happens of e within [from, to]: the occurrences
+// of a recurring event together with the events replacing some, given the
+// replacements of e, or a single event if it starts within the bounds.
+func (r *Resolver) occurrences(ctx context.Context, e *model.Event, replaced map[int64]*model.Event, from, to time.Time) ([]*model.Event, error) {
+	if e.Recurrence == nil {
+		if e.Start.Before(from) || e.Start.After(to) {
+			return []*model.Event{}, nil
+		}
+		return []*model.Event{e}, nil
+	}
+	occurrences, err := r.expand(ctx, e, replaced, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range replaced {
+		if !o.Start.Before(from) && !o.Start.After(to) {
+			occurrences = append(occurrences, o)
+		}
+	}
+	sortEvents(occurrences)
+	return occurrences, nil
+}
+
+// listEvents lists the events matching f. If f has an end, recurring
+// events are expanded into their occurrences within the bounds of f.
+// Otherwise they are listed once, if they start within the bounds.
+func (r *Resolver) listEvents(ctx context.Context, f storage.EventFilter) ([]*model.Event, error) {
+	items, err := r.readEvents(ctx, f, storage.Page{})
+	if err != nil {
+		return nil, err
+	}
+	list := []*model.Event{}
+	for _, it := range items {
+		if e := it.node.(*model.Event); listed(e, f) {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+// readEvents returns the events matching f within p like listEvents does,
+// except that recurring events starting before f are read too if they
+// are not expanded. Other events are read from the store a page at a time,
+// while the occurrences of recurring events, bounded by the end of f, are
+// expanded in full and paged through in memory.
+func (r *Resolver) readEvents(ctx context.Context, f storage.EventFilter, p storage.Page) ([]item, error) {
+	if f.End == nil {
+		f.Page = &p
+		events, err := r.Store.Events.List(ctx, f)
+		return itemsOf(events), err
+	}
+
+	single, recurring := f, f
+	single.Recurring, single.Page = new(bool), &p
+	recurring.Recurring = new(bool)
+	*recurring.Recurring = true
+	events, err := r.Store.Events.List(ctx, single)
+	if err != nil {
+		return nil, err
+	}
+	series, err := r.Store.Events.List(ctx, recurring)
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := r.replacementsOf(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	var from time.Time
+	if f.Start != nil {
+		from = *f.Start
+	}
+	for _, e := range series {
+		occurrences, err := r.expand(ctx, e, replaced[e.ID], from, *f.End)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, occurrences...)
+	}
+	return sliceList(itemsOf(events)).fetch(ctx, p)
+}
+
+// listed reports whether listEvents lists the event e read for f.
+func listed(e *model.Event, f storage.EventFilter) bool {
+	return f.End != nil || e.Recurrence == nil || f.Start == nil || !e.Start.Before(*f.Start)
+}
+
+// sortEvents orders events by start like the store does, occurrences
+// of the same series being told apart by their key.
+func sortEvents(events []*model.Event) {
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return a.Key() < b.Key()
+	})
+}
+
+// responseTarget returns the event ID and occurrence responses to e are
+// stored under. Responses to recurring events are per occurrence.
+func responseTarget(e *model.Event) (string, *time.Time, error) {
+	if e.IsOccurrence() {
+		return e.ID, e.RecurrenceID, nil
+	}
+	if e.Recurrence != nil {
+		return "", nil, apperr.New(apperr.Validation, "responses to a recurring event are given to its occurrences")
+	}
+	return e.ID, nil, nil
+}
+
+// attendeeEvent returns the event or occurrence a is a response to.
+func (r *Resolver) attendeeEvent(ctx context.Context, a *model.Attendee) (*model.Event, error) {
+	e, err := r.event(ctx, a.EventID)
+	if err != nil || a.Occurrence == nil {
+		return e, err
+	}
+	return r.occurrenceAt(ctx, e, *a.Occurrence)
+}
+
+// attendeeKey returns the key of the event or occurrence a responds to.
+func attendeeKey(a *model.Attendee) string {
+	return model.Event{ID: a.EventID, RecurrenceID: a.Occurrence}.Key()
+}
+
+// seriesOf returns the recurring event the occurrence or replacing event e
+// belongs to, and the original start of e within it.
+func (r *Resolver) seriesOf(ctx context.Context, e *model.Event) (*model.Event, time.Time, error) {
+	if e.SeriesID == nil {
+		s, err := r.Store.Events.Get(ctx, e.ID)
+		return s, *e.RecurrenceID, err
+	}
+	s, err := r.Store.Events.Get(ctx, *e.SeriesID)
+	return s, *e.RecurrenceID, err
+}
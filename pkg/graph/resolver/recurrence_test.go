@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+)
+
+// countingEvents counts the lists of events it is asked for.
+type countingEvents struct {
+	storage.EventRepository
+	lists int
+}
+
+func (r *countingEvents) List(ctx context.Context, f storage.EventFilter) ([]*model.Event, error) {
+	r.lists++
+	return r.EventRepository.List(ctx, f)
+}
+
+// TestListEventsReplacements checks that the replacements of all the
+// recurring events listed are looked up at once.
+func TestListEventsReplacements(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	u := &model.User{Username: "alice", Email: "alice@example.org"}
+	if err := store.Users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	o := &model.Organization{Name: "Orchestra", Timezone: "UTC"}
+	if err := store.Organizations.Create(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+	weekly := "FREQ=WEEKLY;COUNT=4"
+	for i := 0; i < 3; i++ {
+		series := &model.Event{Name: "Rehearsal", Start: start, CreatorID: u.ID, OrganizationID: &o.ID, Recurrence: &weekly}
+		if err := store.Events.Create(ctx, series); err != nil {
+			t.Fatal(err)
+		}
+		at := start.AddDate(0, 0, 7)
+		moved := &model.Event{
+			Name:           "Rehearsal",
+			Start:          at.Add(time.Hour),
+			CreatorID:      u.ID,
+			OrganizationID: &o.ID,
+			SeriesID:       &series.ID,
+			RecurrenceID:   &at,
+		}
+		if err := store.Events.Create(ctx, moved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := &countingEvents{EventRepository: store.Events}
+	store.Events = events
+	r := &Resolver{Store: store}
+	end := start.AddDate(0, 1, 0)
+	list, err := r.listEvents(ctx, storage.EventFilter{OrganizationID: &o.ID, End: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each series has three occurrences left and one replacement.
+	if len(list) != 3*4 {
+		t.Errorf("listed %d events, want %d", len(list), 3*4)
+	}
+	// The single events, the recurring ones and their replacements.
+	if events.lists != 3 {
+		t.Errorf("listed events %d times, want 3", events.lists)
+	}
+}
+
+// TestReadEvents checks that pages of events read from the store hold
+// what the same pages of the events listed at once do.
+func TestReadEvents(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	u := &model.User{Username: "alice", Email: "alice@example.org"}
+	if err := store.Users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	o := &model.Organization{Name: "Orchestra", Timezone: "UTC"}
+	if err := store.Organizations.Create(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+	weekly := "FREQ=WEEKLY;COUNT=6"
+	for i := 0; i < 8; i++ {
+		e := &model.Event{Name: "Concert", Start: start.AddDate(0, 0, 5*i-3), CreatorID: u.ID, OrganizationID: &o.ID}
+		if i%3 == 0 {
+			e.Recurrence = &weekly
+		}
+		if err := store.Events.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Resolver{Store: store}
+	end := start.AddDate(0, 1, 0)
+	for _, f := range []storage.EventFilter{
+		{OrganizationID: &o.ID},
+		{OrganizationID: &o.ID, Start: &start, End: &end},
+	} {
+		events, err := r.listEvents(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all := sliceList(itemsOf(events))
+		for _, p := range []storage.Page{
+			{Limit: 3},
+			{Limit: 3, Last: true},
+			{Limit: 4, After: all[2].key},
+			{Limit: 4, Before: all[len(all)-2].key, Last: true},
+			{After: all[1].key, Before: all[len(all)-1].key},
+		} {
+			got, err := r.readEvents(ctx, f, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := all.fetch(ctx, p)
+			if !reflect.DeepEqual(keys(got), keys(want)) {
+				t.Errorf("filter %+v, page %+v: read %q, want %q", f, p, keys(got), keys(want))
+			}
+		}
+	}
+}
+
+func keys(items []item) []storage.Key {
+	var keys []storage.Key
+	for _, it := range items {
+		keys = append(keys, it.key)
+	}
+	return keys
+}
@@ -1,7 +1,28 @@
 package resolver
 
+import (
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/mail"
+	"github.com/concertLabs/oaf-server/pkg/pubsub"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{}
+type Resolver struct {
+	Store *storage.Store
+	Auth  *auth.Service
+	// Bus carries the changes the subscriptions report.
+	Bus *pubsub.Bus
+	// Mailer sends the tokens of invites by email.
+	Mailer mail.Mailer
+	// InviteTTL is how long invites can be accepted.
+	InviteTTL time.Duration
+	// CalendarReminder is how long before events calendar feeds remind of
+	// them, never if zero.
+	CalendarReminder time.Duration
+}
@@ -5,169 +5,1230 @@ package resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/auth"
 	"github.com/concertLabs/oaf-server/pkg/graph/generated"
 	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
 )
 
+func (r *attendeeResolver) User(ctx context.Context, obj *model.Attendee) (*model.User, error) {
+	return r.user(ctx, obj.UserID)
+}
+
+func (r *attendeeResolver) Event(ctx context.Context, obj *model.Attendee) (*model.Event, error) {
+	return r.attendeeEvent(ctx, obj)
+}
+
+func (r *commentResolver) Creator(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	return r.user(ctx, obj.CreatorID)
+}
+
+func (r *commentResolver) Event(ctx context.Context, obj *model.Comment) (*model.Event, error) {
+	return r.event(ctx, obj.EventID)
+}
+
+func (r *eventResolver) Start(ctx context.Context, obj *model.Event, timezone *string) (*time.Time, error) {
+	return r.eventTime(ctx, obj, obj.Start, timezone)
+}
+
+func (r *eventResolver) End(ctx context.Context, obj *model.Event, timezone *string) (*time.Time, error) {
+	if obj.End == nil {
+		return nil, nil
+	}
+	return r.eventTime(ctx, obj, *obj.End, timezone)
+}
+
+func (r *eventResolver) Creator(ctx context.Context, obj *model.Event) (*model.User, error) {
+	return r.user(ctx, obj.CreatorID)
+}
+
+func (r *eventResolver) Organization(ctx context.Context, obj *model.Event) (*model.Organization, error) {
+	if obj.OrganizationID == nil {
+		return nil, nil
+	}
+	return r.organization(ctx, *obj.OrganizationID)
+}
+
+func (r *eventResolver) Sections(ctx context.Context, obj *model.Event) ([]*model.Section, error) {
+	return r.sections(ctx, obj.SectionIDs)
+}
+
+func (r *eventResolver) ExpectedAttendees(ctx context.Context, obj *model.Event) ([]*model.User, error) {
+	return r.expectedAttendees(ctx, obj)
+}
+
+func (r *eventResolver) Comments(ctx context.Context, obj *model.Event, first *int, after *string, last *int, before *string) (*model.CommentConnection, error) {
+	return commentConnection(ctx, r.commentList(obj.ID), page(first, after, last, before))
+}
+
+func (r *eventResolver) Attendees(ctx context.Context, obj *model.Event, first *int, after *string, last *int, before *string) (*model.AttendeeConnection, error) {
+	pg := page(first, after, last, before)
+	l, err := r.eventAttendeeList(ctx, obj, pg)
+	if err != nil {
+		return nil, err
+	}
+	return attendeeConnection(ctx, l, pg)
+}
+
+func (r *eventResolver) Exdates(ctx context.Context, obj *model.Event, timezone *string) ([]*time.Time, error) {
+	exdates := make([]*time.Time, len(obj.ExDates))
+	for i, t := range obj.ExDates {
+		var err error
+		if exdates[i], err = r.eventTime(ctx, obj, t, timezone); err != nil {
+			return nil, err
+		}
+	}
+	return exdates, nil
+}
+
+func (r *eventResolver) RecurrenceID(ctx context.Context, obj *model.Event, timezone *string) (*time.Time, error) {
+	if obj.RecurrenceID == nil {
+		return nil, nil
+	}
+	return r.eventTime(ctx, obj, *obj.RecurrenceID, timezone)
+}
+
+func (r *eventResolver) Series(ctx context.Context, obj *model.Event) (*model.Event, error) {
+	if obj.RecurrenceID == nil {
+		return nil, nil
+	}
+	series, _, err := r.seriesOf(ctx, obj)
+	return series, err
+}
+
+func (r *eventResolver) Occurrences(ctx context.Context, obj *model.Event, start time.Time, end time.Time) ([]*model.Event, error) {
+	if err := checkExpansion("start", &start, "end", end); err != nil {
+		return nil, err
+	}
+	replaced, err := r.replacementsOf(ctx, []*model.Event{obj})
+	if err != nil {
+		return nil, err
+	}
+	return r.occurrences(ctx, obj, replaced[obj.ID], start, end)
+}
+
+func (r *inviteResolver) User(ctx context.Context, obj *model.Invite) (*model.User, error) {
+	if obj.UserID == nil {
+		return nil, nil
+	}
+	return r.user(ctx, *obj.UserID)
+}
+
+func (r *inviteResolver) Section(ctx context.Context, obj *model.Invite) (*model.Section, error) {
+	return r.section(ctx, obj.SectionID)
+}
+
+func (r *inviteResolver) InvitedBy(ctx context.Context, obj *model.Invite) (*model.User, error) {
+	if obj.InvitedByID == nil {
+		return nil, nil
+	}
+	u, err := r.user(ctx, *obj.InvitedByID)
+	return u, ignoreNotFound(err)
+}
+
+func (r *memberResolver) User(ctx context.Context, obj *model.Member) (*model.User, error) {
+	return r.user(ctx, obj.UserID)
+}
+
+func (r *memberResolver) Section(ctx context.Context, obj *model.Member) (*model.Section, error) {
+	return r.section(ctx, obj.SectionID)
+}
+
 func (r *mutationResolver) CreateUser(ctx context.Context, user model.NewUser) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+	var invite *model.Invite
+	if user.InviteToken != nil {
+		i, err := r.Store.Invites.GetByTokenHash(ctx, auth.HashToken(*user.InviteToken))
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrInvalidInviteToken
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i.Expired(time.Now()) {
+			return nil, ErrInviteExpired
+		}
+		invite = i
+	}
+
+	hash, err := auth.HashPassword(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	u := &model.User{
+		Username: user.Username,
+		Password: hash,
+		Email:    user.Email,
+		Showname: user.Showname,
+	}
+	// Sign-ups fail as a whole if the invite cannot be redeemed.
+	err = r.Store.Atomic(ctx, func(s *storage.Store) error {
+		if err := s.Users.Create(ctx, u); err != nil {
+			return err
+		}
+		if invite == nil {
+			return nil
+		}
+		_, err := redeemInvite(ctx, s, invite, u.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (r *mutationResolver) UpdateUser(ctx context.Context, id string, email *string, showname *string) (*model.User, error) {
+	id, err := relay.Decode(id, "User")
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSelf(ctx, id); err != nil {
+		return nil, err
+	}
+	u, err := r.Store.Users.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if email != nil {
+		u.Email = *email
+	}
+	if showname != nil {
+		u.Showname = showname
+	}
+	if err := r.Store.Users.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
-func (r *mutationResolver) UpdateUser(ctx context.Context, id string, password *string, email *string, showname *string) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) ChangePassword(ctx context.Context, oldPassword string, newPassword string) (*model.User, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Auth.ChangePassword(ctx, u.ID, oldPassword, newPassword)
 }
 
 func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "User")
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSelf(ctx, id); err != nil {
+		return nil, err
+	}
+	u, err := r.Store.Users.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Users.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
 func (r *mutationResolver) CreateOrganization(ctx context.Context, organization model.NewOrganization) (*model.Organization, error) {
-	panic(fmt.Errorf("not implemented"))
+	if err := requireSuperuser(ctx); err != nil {
+		return nil, err
+	}
+	o := &model.Organization{
+		Name:     organization.Name,
+		Picture:  organization.Picture,
+		Timezone: "UTC",
+	}
+	if organization.Timezone != nil {
+		if _, err := loadLocation(*organization.Timezone); err != nil {
+			return nil, err
+		}
+		o.Timezone = *organization.Timezone
+	}
+	if err := r.Store.Organizations.Create(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
-func (r *mutationResolver) UpdateOrganization(ctx context.Context, id string, name *string, picture *string) (*model.Organization, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) UpdateOrganization(ctx context.Context, id string, name *string, picture *string, timezone *string) (*model.Organization, error) {
+	id, err := relay.Decode(id, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.Store.Organizations.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		o.Name = *name
+	}
+	if picture != nil {
+		o.Picture = picture
+	}
+	if timezone != nil {
+		if _, err := loadLocation(*timezone); err != nil {
+			return nil, err
+		}
+		o.Timezone = *timezone
+	}
+	if err := r.Store.Organizations.Update(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 func (r *mutationResolver) DeleteOrganization(ctx context.Context, id string) (*model.Organization, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.Store.Organizations.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Organizations.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 func (r *mutationResolver) CreateSection(ctx context.Context, section model.NewSection) (*model.Section, error) {
-	panic(fmt.Errorf("not implemented"))
+	organizationID, err := relay.Decode(section.Organization, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	s := &model.Section{
+		Name:           section.Name,
+		OrganizationID: organizationID,
+	}
+	if err := r.Store.Sections.Create(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func (r *mutationResolver) UpdateSection(ctx context.Context, id string, name string) (*model.Section, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Section")
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.Store.Sections.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.Name = name
+	if err := r.Store.Sections.Update(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func (r *mutationResolver) DeleteSection(ctx context.Context, id string) (*model.Section, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Section")
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.Store.Sections.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Sections.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func (r *mutationResolver) CreateSectionMember(ctx context.Context, section string, user string, right *int) (*model.Member, error) {
-	panic(fmt.Errorf("not implemented"))
+	var err error
+	if section, err = relay.Decode(section, "Section"); err != nil {
+		return nil, err
+	}
+	if user, err = relay.Decode(user, "User"); err != nil {
+		return nil, err
+	}
+	m := &model.Member{
+		UserID:    user,
+		SectionID: section,
+	}
+	if right != nil {
+		m.Right = *right
+	}
+	if err := r.checkGrant(ctx, section, m.Right); err != nil {
+		return nil, err
+	}
+	if err := r.Store.Members.Create(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 func (r *mutationResolver) UpdateSectionMember(ctx context.Context, section string, user string, right int) (*model.Member, error) {
-	panic(fmt.Errorf("not implemented"))
+	var err error
+	if section, err = relay.Decode(section, "Section"); err != nil {
+		return nil, err
+	}
+	if user, err = relay.Decode(user, "User"); err != nil {
+		return nil, err
+	}
+	m, err := r.Store.Members.GetBySectionUser(ctx, section, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkGrant(ctx, section, m.Right|right); err != nil {
+		return nil, err
+	}
+	m.Right = right
+	if err := r.Store.Members.Update(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 func (r *mutationResolver) DeleteSectionMember(ctx context.Context, section string, user string) (*model.Member, error) {
-	panic(fmt.Errorf("not implemented"))
+	var err error
+	if section, err = relay.Decode(section, "Section"); err != nil {
+		return nil, err
+	}
+	if user, err = relay.Decode(user, "User"); err != nil {
+		return nil, err
+	}
+	m, err := r.Store.Members.GetBySectionUser(ctx, section, user)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkGrant(ctx, section, m.Right); err != nil {
+		return nil, err
+	}
+	if err := r.Store.Members.Delete(ctx, m.ID); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 func (r *mutationResolver) CreateEvent(ctx context.Context, event model.NewEvent) (*model.Event, error) {
-	panic(fmt.Errorf("not implemented"))
+	organizationID, err := relay.Decode(event.Organization, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	sections, err := r.organizationSections(ctx, organizationID, event.Sections)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e := &model.Event{
+		Name:           event.Name,
+		Description:    event.Description,
+		Adress:         event.Adress,
+		Start:          event.Start,
+		End:            event.End,
+		CreatorID:      creator.ID,
+		OrganizationID: &organizationID,
+		SectionIDs:     sections,
+	}
+	if event.Recurrence != nil {
+		if e.Recurrence, err = parseRecurrence(*event.Recurrence); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Store.Events.Create(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
 }
 
-func (r *mutationResolver) UpdateEvent(ctx context.Context, id string, name *string, description *string, adress *string, start *string, end *string) (*model.Event, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) UpdateEvent(ctx context.Context, id string, name *string, description *string, adress *string, start *time.Time, end *time.Time, sections []string, recurrence *string, apply *model.RecurrenceRange) (*model.Event, error) {
+	c := eventChange{
+		name:        name,
+		description: description,
+		adress:      adress,
+		start:       start,
+		end:         end,
+		sections:    sections,
+	}
+	if recurrence != nil {
+		canonical, err := parseRecurrence(*recurrence)
+		if err != nil {
+			return nil, err
+		}
+		c.recurrence = new(string)
+		if canonical != nil {
+			c.recurrence = canonical
+		}
+	}
+
+	var updated *model.Event
+	err := r.Store.Atomic(ctx, func(s *storage.Store) error {
+		tx := r.withStore(s)
+		e, err := tx.loadEvent(ctx, id)
+		if err != nil {
+			return err
+		}
+		switch {
+		case e.RecurrenceID == nil:
+			updated, err = tx.updateSeries(ctx, e, e.Start, c)
+		case apply != nil && *apply == model.RecurrenceRangeThis:
+			updated, err = tx.updateOccurrence(ctx, e, c)
+		case apply != nil && *apply == model.RecurrenceRangeFollowing:
+			updated, err = tx.updateFollowing(ctx, e, c)
+		default:
+			series, at, err := tx.seriesOf(ctx, e)
+			if err != nil {
+				return err
+			}
+			updated, err = tx.updateSeries(ctx, series, at, c)
+			return err
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.publishEvent(updated)
+	return updated, nil
 }
 
-func (r *mutationResolver) DeleteEvent(ctx context.Context, id string) (*model.Event, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) DeleteEvent(ctx context.Context, id string, apply *model.RecurrenceRange) (*model.Event, error) {
+	var e, changed *model.Event
+	err := r.Store.Atomic(ctx, func(s *storage.Store) error {
+		tx := r.withStore(s)
+		var err error
+		if e, err = tx.loadEvent(ctx, id); err != nil {
+			return err
+		}
+		if e.RecurrenceID == nil {
+			return s.Events.Delete(ctx, e.ID)
+		}
+
+		series, at, err := tx.seriesOf(ctx, e)
+		if err != nil {
+			return err
+		}
+		switch {
+		case apply != nil && *apply == model.RecurrenceRangeThis:
+			err = tx.removeOccurrence(ctx, series, e, at)
+		case apply != nil && *apply == model.RecurrenceRangeFollowing && !at.Equal(series.Start):
+			err = tx.truncateSeries(ctx, series, at)
+		default:
+			return s.Events.Delete(ctx, series.ID)
+		}
+		if err != nil {
+			return err
+		}
+		changed = series
+		return s.Events.Update(ctx, series)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if changed != nil {
+		r.publishEvent(changed)
+	}
+	return e, nil
 }
 
-func (r *mutationResolver) CreateEventAttendee(ctx context.Context, event string, user string, commitment int, comment *string) (*model.Attendee, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) ImportEvents(ctx context.Context, organization string, sections []string, file graphql.Upload, dryRun *bool) (*model.ImportResult, error) {
+	organizationID, err := relay.Decode(organization, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	creator, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.ImportCalendar(ctx, creator.ID, organizationID, sections, file.File, dryRun != nil && *dryRun)
 }
 
-func (r *mutationResolver) UpdateEventAttendee(ctx context.Context, event string, user string, commitment int, comment *string) (*model.Attendee, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) CreateEventAttendee(ctx context.Context, event string, user string, commitment model.Commitment, comment *string) (*model.Attendee, error) {
+	user, err := relay.Decode(user, "User")
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event, at, err := responseTarget(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAttendee(ctx, e, user); err != nil {
+		return nil, err
+	}
+	a := &model.Attendee{
+		UserID:     user,
+		EventID:    event,
+		Occurrence: at,
+		Commitment: commitment,
+		Comment:    comment,
+	}
+	if err := r.Store.Attendees.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	r.publishAttendee(model.ChangeKindCreated, a)
+	return a, nil
+}
+
+func (r *mutationResolver) UpdateEventAttendee(ctx context.Context, event string, user string, commitment model.Commitment, comment *string) (*model.Attendee, error) {
+	user, err := relay.Decode(user, "User")
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event, at, err := responseTarget(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAttendee(ctx, e, user); err != nil {
+		return nil, err
+	}
+	a, err := r.Store.Attendees.GetByEventUser(ctx, event, user, at)
+	if err != nil {
+		return nil, err
+	}
+	a.Commitment = commitment
+	a.Comment = comment
+	if err := r.Store.Attendees.Update(ctx, a); err != nil {
+		return nil, err
+	}
+	r.publishAttendee(model.ChangeKindUpdated, a)
+	return a, nil
+}
+
+func (r *mutationResolver) RespondToEvent(ctx context.Context, event string, commitment model.Commitment, comment *string) (*model.Attendee, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event, at, err := responseTarget(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAttendee(ctx, e, u.ID); err != nil {
+		return nil, err
+	}
+
+	a, err := r.Store.Attendees.GetByEventUser(ctx, event, u.ID, at)
+	if errors.Is(err, storage.ErrNotFound) {
+		a = &model.Attendee{
+			UserID:     u.ID,
+			EventID:    event,
+			Occurrence: at,
+			Commitment: commitment,
+			Comment:    comment,
+		}
+		if err := r.Store.Attendees.Create(ctx, a); err != nil {
+			return nil, err
+		}
+		r.publishAttendee(model.ChangeKindCreated, a)
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.Commitment = commitment
+	a.Comment = comment
+	if err := r.Store.Attendees.Update(ctx, a); err != nil {
+		return nil, err
+	}
+	r.publishAttendee(model.ChangeKindUpdated, a)
+	return a, nil
 }
 
 func (r *mutationResolver) DeleteEventAttendee(ctx context.Context, event string, user string) (*model.Attendee, error) {
-	panic(fmt.Errorf("not implemented"))
+	user, err := relay.Decode(user, "User")
+	if err != nil {
+		return nil, err
+	}
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	event, at, err := responseTarget(e)
+	if err != nil {
+		return nil, err
+	}
+	a, err := r.Store.Attendees.GetByEventUser(ctx, event, user, at)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Attendees.Delete(ctx, a.ID); err != nil {
+		return nil, err
+	}
+	r.publishAttendee(model.ChangeKindDeleted, a)
+	return a, nil
 }
 
 func (r *mutationResolver) CreateEventComment(ctx context.Context, event string, text string) (*model.Comment, error) {
-	panic(fmt.Errorf("not implemented"))
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &model.Comment{
+		Text:      text,
+		CreatorID: creator.ID,
+		EventID:   e.ID,
+	}
+	if err := r.Store.Comments.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	r.publishComment(c)
+	return c, nil
 }
 
 func (r *mutationResolver) UpdateEventComment(ctx context.Context, id string, text string) (*model.Comment, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Comment")
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.Store.Comments.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Text = text
+	if err := r.Store.Comments.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (r *mutationResolver) DeleteEventComment(ctx context.Context, id string) (*model.Comment, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Comment")
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.Store.Comments.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Comments.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (r *mutationResolver) CreateInvite(ctx context.Context, invite model.NewInvite) (*model.Invite, error) {
-	panic(fmt.Errorf("not implemented"))
+	i, token, err := r.newInvite(ctx, invite)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Invites.Create(ctx, i); err != nil {
+		return nil, err
+	}
+	if i.Email != nil {
+		if err := r.mailInvite(ctx, i, token); err != nil {
+			// An invite nobody got the token of is of no use.
+			if err := r.Store.Invites.Delete(ctx, i.ID); err != nil {
+				log.Printf("deleting unsent invite %s: %v", i.ID, err)
+			}
+			return nil, fmt.Errorf("sending the invite: %w", err)
+		}
+	}
+	return i, nil
 }
 
 func (r *mutationResolver) DeleteInvite(ctx context.Context, id string) (*model.Invite, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Invite")
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.Store.Invites.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Invites.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *mutationResolver) AcceptInvite(ctx context.Context, id string) (*model.Member, error) {
+	id, err := relay.Decode(id, "Invite")
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.inviteToUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var m *model.Member
+	err = r.Store.Atomic(ctx, func(s *storage.Store) error {
+		m, err = redeemInvite(ctx, s, i, *i.UserID)
+		return err
+	})
+	return m, err
+}
+
+func (r *mutationResolver) DeclineInvite(ctx context.Context, id string) (*model.Invite, error) {
+	id, err := relay.Decode(id, "Invite")
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.inviteToUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Store.Invites.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *mutationResolver) Login(ctx context.Context, input model.Login) (*model.AuthPayload, error) {
+	t, err := r.Auth.Login(ctx, input.Username, input.Password)
+	if err != nil {
+		return nil, err
+	}
+	return r.authPayload(ctx, t)
+}
+
+func (r *mutationResolver) RefreshToken(ctx context.Context, input model.RefreshTokenInput) (*model.AuthPayload, error) {
+	t, err := r.Auth.Refresh(ctx, input.Token)
+	if err != nil {
+		return nil, err
+	}
+	return r.authPayload(ctx, t)
 }
 
-func (r *mutationResolver) Login(ctx context.Context, input model.Login) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) CreateCalendarToken(ctx context.Context) (string, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	return r.createCalendarToken(ctx, u)
 }
 
-func (r *mutationResolver) RefreshToken(ctx context.Context, input model.RefreshTokenInput) (string, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) RevokeCalendarToken(ctx context.Context) (*model.Viewer, error) {
+	u, err := auth.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u.CalendarTokenHash = nil
+	if err := r.Store.Users.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	return &model.Viewer{User: u}, nil
+}
+
+func (r *organizationResolver) Sections(ctx context.Context, obj *model.Organization, first *int, after *string, last *int, before *string) (*model.SectionConnection, error) {
+	return sectionConnection(ctx, storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			sections, err := r.Store.Sections.ListByOrganization(ctx, obj.ID, &p)
+			return itemsOf(sections), err
+		},
+	}, page(first, after, last, before))
+}
+
+func (r *queryResolver) Me(ctx context.Context) (*model.Viewer, error) {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return &model.Viewer{User: u}, nil
+}
+
+func (r *queryResolver) Node(ctx context.Context, id string) (model.Node, error) {
+	n, err := r.node(ctx, id)
+	return n, ignoreNotFound(err)
+}
+
+func (r *queryResolver) Nodes(ctx context.Context, ids []string) ([]model.Node, error) {
+	nodes := make([]model.Node, len(ids))
+	for i, id := range ids {
+		n, err := r.node(ctx, id)
+		if err := ignoreNotFound(err); err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
 }
 
 func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "User")
+	if err != nil {
+		return nil, err
+	}
+	u, err := r.Store.Users.Get(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
 func (r *queryResolver) Organization(ctx context.Context, id string) (*model.Organization, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Organization")
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.Store.Organizations.Get(ctx, id)
+	return o, ignoreNotFound(err)
 }
 
 func (r *queryResolver) Section(ctx context.Context, id string) (*model.Section, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Section")
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.Store.Sections.Get(ctx, id)
+	return s, ignoreNotFound(err)
 }
 
 func (r *queryResolver) Member(ctx context.Context, id string) (*model.Member, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Member")
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.Store.Members.Get(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (r *queryResolver) Members(ctx context.Context, section *string, user *string, right *int) ([]*model.Member, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *queryResolver) Members(ctx context.Context, section *string, user *string, right *int, first *int, after *string, last *int, before *string) (*model.MemberConnection, error) {
+	a, err := r.access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if section, err = relay.DecodeOptional(section, "Section"); err != nil {
+		return nil, err
+	}
+	if user, err = relay.DecodeOptional(user, "User"); err != nil {
+		return nil, err
+	}
+	return memberConnection(ctx, storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			members, err := r.Store.Members.List(ctx, storage.MemberFilter{
+				SectionID: section,
+				UserID:    user,
+				Right:     right,
+				Page:      &p,
+			})
+			return itemsOf(members), err
+		},
+		visible: func(ctx context.Context, n interface{}) (bool, error) {
+			return a.canViewMember(n.(*model.Member)), nil
+		},
+	}, page(first, after, last, before))
 }
 
 func (r *queryResolver) Event(ctx context.Context, id string) (*model.Event, error) {
-	panic(fmt.Errorf("not implemented"))
+	e, err := r.loadEvent(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
 }
 
-func (r *queryResolver) Events(ctx context.Context, organization *string, start *string, end *string) ([]*model.Event, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *queryResolver) Events(ctx context.Context, organization *string, start *time.Time, end *time.Time, first *int, after *string, last *int, before *string) (*model.EventConnection, error) {
+	a, err := r.access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if organization, err = relay.DecodeOptional(organization, "Organization"); err != nil {
+		return nil, err
+	}
+	if end != nil {
+		if err := checkExpansion("start", start, "end", *end); err != nil {
+			return nil, err
+		}
+	}
+	f := storage.EventFilter{
+		OrganizationID: organization,
+		Start:          start,
+		End:            end,
+	}
+	return eventConnection(ctx, storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			return r.readEvents(ctx, f, p)
+		},
+		visible: func(ctx context.Context, n interface{}) (bool, error) {
+			e := n.(*model.Event)
+			if !listed(e, f) {
+				return false, nil
+			}
+			return r.canViewEvent(ctx, a, e)
+		},
+	}, page(first, after, last, before))
 }
 
 func (r *queryResolver) Comment(ctx context.Context, id string) (*model.Comment, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Comment")
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.Store.Comments.Get(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func (r *queryResolver) Comments(ctx context.Context, event string) ([]*model.Comment, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *queryResolver) Comments(ctx context.Context, event string, first *int, after *string, last *int, before *string) (*model.CommentConnection, error) {
+	e, err := r.loadEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireVisible(ctx, e); err != nil {
+		return nil, err
+	}
+	return commentConnection(ctx, r.commentList(e.ID), page(first, after, last, before))
 }
 
 func (r *queryResolver) Attendee(ctx context.Context, id string) (*model.Attendee, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Attendee")
+	if err != nil {
+		return nil, err
+	}
+	a, err := r.Store.Attendees.Get(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
 }
 
-func (r *queryResolver) Attendees(ctx context.Context, event *string, user *string, commitment *model.Commitment) ([]*model.Attendee, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *queryResolver) Attendees(ctx context.Context, event *string, user *string, commitment *model.Commitment, first *int, after *string, last *int, before *string) (*model.AttendeeConnection, error) {
+	a, err := r.access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f := storage.AttendeeFilter{Commitment: commitment}
+	if event != nil {
+		id, at, err := decodeEvent(*event)
+		if err != nil {
+			return nil, err
+		}
+		f.EventID, f.Occurrence = &id, at
+	}
+	if user, err = relay.DecodeOptional(user, "User"); err != nil {
+		return nil, err
+	}
+	f.UserID = user
+	return attendeeConnection(ctx, storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			f.Page = &p
+			attendees, err := r.Store.Attendees.List(ctx, f)
+			r.prefetchEvents(ctx, attendees)
+			return itemsOf(attendees), err
+		},
+		visible: func(ctx context.Context, n interface{}) (bool, error) {
+			return r.canViewAttendee(ctx, a, n.(*model.Attendee))
+		},
+	}, page(first, after, last, before))
 }
 
 func (r *queryResolver) Invite(ctx context.Context, id string) (*model.Invite, error) {
-	panic(fmt.Errorf("not implemented"))
+	id, err := relay.Decode(id, "Invite")
+	if err != nil {
+		return nil, err
+	}
+	i, err := r.Store.Invites.Get(ctx, id)
+	if err != nil {
+		return nil, ignoreNotFound(err)
+	}
+	if err := r.requireVisible(ctx, i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r *queryResolver) Invites(ctx context.Context, section *string, user *string, first *int, after *string, last *int, before *string) (*model.InviteConnection, error) {
+	a, err := r.access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if section, err = relay.DecodeOptional(section, "Section"); err != nil {
+		return nil, err
+	}
+	if user, err = relay.DecodeOptional(user, "User"); err != nil {
+		return nil, err
+	}
+	return inviteConnection(ctx, storeList{
+		read: func(ctx context.Context, p storage.Page) ([]item, error) {
+			invites, err := r.Store.Invites.List(ctx, storage.InviteFilter{
+				SectionID: section,
+				UserID:    user,
+				Page:      &p,
+			})
+			return itemsOf(invites), err
+		},
+		visible: func(ctx context.Context, n interface{}) (bool, error) {
+			return a.canViewInvite(n.(*model.Invite)), nil
+		},
+	}, page(first, after, last, before))
+}
+
+func (r *sectionResolver) Organization(ctx context.Context, obj *model.Section) (*model.Organization, error) {
+	return r.organization(ctx, obj.OrganizationID)
 }
 
-func (r *queryResolver) Invites(ctx context.Context, section *string, user *string) ([]*model.Invite, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *sectionResolver) Member(ctx context.Context, obj *model.Section, first *int, after *string, last *int, before *string) (*model.MemberConnection, error) {
+	a, err := r.access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := r.sectionMembers(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return memberConnection(ctx, sliceList(itemsOf(a.members(members))), page(first, after, last, before))
 }
 
+func (r *subscriptionResolver) EventUpdated(ctx context.Context, event string) (<-chan *model.Event, error) {
+	event, _, err := r.subscribableEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	msgs := r.Bus.Subscribe(ctx, eventTopic(event))
+	events := make(chan *model.Event)
+	go func() {
+		defer close(events)
+		for msg := range msgs {
+			select {
+			case events <- msg.(*model.Event):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *subscriptionResolver) AttendeeChanged(ctx context.Context, event string) (<-chan *model.AttendeeChange, error) {
+	event, occurrence, err := r.subscribableEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	msgs := r.Bus.Subscribe(ctx, attendeeTopic(event, occurrence))
+	changes := make(chan *model.AttendeeChange)
+	go func() {
+		defer close(changes)
+		for msg := range msgs {
+			select {
+			case changes <- msg.(*model.AttendeeChange):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, event string) (<-chan *model.Comment, error) {
+	event, _, err := r.subscribableEvent(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	msgs := r.Bus.Subscribe(ctx, commentTopic(event))
+	comments := make(chan *model.Comment)
+	go func() {
+		defer close(comments)
+		for msg := range msgs {
+			select {
+			case comments <- msg.(*model.Comment):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return comments, nil
+}
+
+func (r *viewerResolver) Memberships(ctx context.Context, obj *model.Viewer) ([]*model.Member, error) {
+	return r.Store.Members.List(ctx, storage.MemberFilter{UserID: &obj.User.ID})
+}
+
+func (r *viewerResolver) Organizations(ctx context.Context, obj *model.Viewer) ([]*model.Organization, error) {
+	return r.userOrganizations(ctx, obj.User.ID)
+}
+
+func (r *viewerResolver) Invites(ctx context.Context, obj *model.Viewer) ([]*model.Invite, error) {
+	invites, err := r.Store.Invites.List(ctx, storage.InviteFilter{UserID: &obj.User.ID})
+	if err != nil {
+		return nil, err
+	}
+	return pendingInvites(invites, time.Now()), nil
+}
+
+func (r *viewerResolver) UpcomingEvents(ctx context.Context, obj *model.Viewer) ([]*model.Event, error) {
+	return r.upcomingEvents(ctx, obj.User.ID, time.Now())
+}
+
+// Attendee returns generated.AttendeeResolver implementation.
+func (r *Resolver) Attendee() generated.AttendeeResolver { return &attendeeResolver{r} }
+
+// Comment returns generated.CommentResolver implementation.
+func (r *Resolver) Comment() generated.CommentResolver { return &commentResolver{r} }
+
+// Event returns generated.EventResolver implementation.
+func (r *Resolver) Event() generated.EventResolver { return &eventResolver{r} }
+
+// Invite returns generated.InviteResolver implementation.
+func (r *Resolver) Invite() generated.InviteResolver { return &inviteResolver{r} }
+
+// Member returns generated.MemberResolver implementation.
+func (r *Resolver) Member() generated.MemberResolver { return &memberResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Organization returns generated.OrganizationResolver implementation.
+func (r *Resolver) Organization() generated.OrganizationResolver { return &organizationResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Section returns generated.SectionResolver implementation.
+func (r *Resolver) Section() generated.SectionResolver { return &sectionResolver{r} }
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+// Viewer returns generated.ViewerResolver implementation.
+func (r *Resolver) Viewer() generated.ViewerResolver { return &viewerResolver{r} }
+
+type attendeeResolver struct{ *Resolver }
+type commentResolver struct{ *Resolver }
+type eventResolver struct{ *Resolver }
+type inviteResolver struct{ *Resolver }
+type memberResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type organizationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type sectionResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type viewerResolver struct{ *Resolver }
@@ -0,0 +1,412 @@
+package resolver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/rrule"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// withStore returns a copy of r using s, such as the store of a
+// transaction. Changes to a series touch several events and responses and
+// are made through the copy within a transaction, so that they take
+// effect all at once or not at all.
+func (r *Resolver) withStore(s *storage.Store) *Resolver {
+	tx := *r
+	tx.Store = s
+	return &tx
+}
+
+// eventChange holds the arguments of updateEvent, nil for the fields it
+// keeps. A non-nil recurrence is either a canonical rule or empty to make
+// the event a single event.
+type eventChange struct {
+	name, description, adress *string
+	start, end                *time.Time
+	sections                  []string
+	recurrence                *string
+}
+
+// applyFields sets the fields of c other than the times and the
+// recurrence on e.
+func (r *Resolver) applyFields(ctx context.Context, e *model.Event, c eventChange) error {
+	if c.name != nil {
+		e.Name = *c.name
+	}
+	if c.description != nil {
+		e.Description = c.description
+	}
+	if c.adress != nil {
+		e.Adress = c.adress
+	}
+	if c.sections != nil {
+		if e.OrganizationID == nil {
+			return apperr.New(apperr.Validation, "sections cannot be set on events without an organization")
+		}
+		var err error
+		if e.SectionIDs, err = r.organizationSections(ctx, *e.OrganizationID, c.sections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retime moves e so that its occurrence starting at at gets the start and
+// end of c, and returns by how much e moved. It fails if e would end
+// before it starts.
+func retime(e *model.Event, at time.Time, c eventChange) (time.Duration, error) {
+	var delta time.Duration
+	if c.start != nil {
+		delta = c.start.Sub(at)
+	}
+	e.Start = e.Start.Add(delta)
+	switch {
+	case c.end != nil:
+		end := e.Start.Add(c.end.Sub(at.Add(delta)))
+		e.End = &end
+	case e.End != nil:
+		end := e.End.Add(delta)
+		e.End = &end
+	}
+	if c.end != nil && !endsAfterStart(e.Start, e.End) {
+		return 0, apperr.Field("end", "must be after start")
+	}
+	return delta, nil
+}
+
+func shiftTimes(times []time.Time, delta time.Duration) []time.Time {
+	shifted := make([]time.Time, len(times))
+	for i, t := range times {
+		shifted[i] = t.Add(delta)
+	}
+	return shifted
+}
+
+// splitTimes splits ordered times into those before at and the others.
+func splitTimes(times []time.Time, at time.Time) ([]time.Time, []time.Time) {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(at) })
+	return append([]time.Time(nil), times[:i]...), append([]time.Time(nil), times[i:]...)
+}
+
+// updateSeries applies c to every occurrence of the event e, which may be
+// a single event. at is the start of the occurrence the times of c are
+// for; the whole series moves by as much as that occurrence does.
+func (r *Resolver) updateSeries(ctx context.Context, e *model.Event, at time.Time, c eventChange) (*model.Event, error) {
+	if err := r.applyFields(ctx, e, c); err != nil {
+		return nil, err
+	}
+	delta, err := retime(e, at, c)
+	if err != nil {
+		return nil, err
+	}
+	if e.Recurrence != nil && delta != 0 {
+		e.ExDates = shiftTimes(e.ExDates, delta)
+		if err := r.moveOccurrences(ctx, e.ID, e.ID, time.Time{}, delta); err != nil {
+			return nil, err
+		}
+	}
+	if c.recurrence != nil {
+		if err := r.setRecurrence(ctx, e, *c.recurrence); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Store.Events.Update(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// updateOccurrence applies c to the occurrence e alone, replacing it by
+// an event of its own unless it is one already.
+func (r *Resolver) updateOccurrence(ctx context.Context, e *model.Event, c eventChange) (*model.Event, error) {
+	if c.recurrence != nil {
+		return nil, apperr.New(apperr.Validation, "a single occurrence cannot be given a recurrence")
+	}
+	if e.SeriesID != nil {
+		if err := r.applyFields(ctx, e, c); err != nil {
+			return nil, err
+		}
+		if _, err := retime(e, e.Start, c); err != nil {
+			return nil, err
+		}
+		if err := r.Store.Events.Update(ctx, e); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	o := occurrence(e, e.Start)
+	seriesID := e.ID
+	o.ID, o.SeriesID, o.Recurrence, o.ExDates, o.UID = "", &seriesID, nil, nil, nil
+	if err := r.applyFields(ctx, o, c); err != nil {
+		return nil, err
+	}
+	if _, err := retime(o, o.Start, c); err != nil {
+		return nil, err
+	}
+	if err := r.Store.Events.Create(ctx, o); err != nil {
+		return nil, err
+	}
+
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &seriesID, Occurrence: e.RecurrenceID})
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attendees {
+		a.EventID, a.Occurrence = o.ID, nil
+		if err := r.Store.Attendees.Update(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// updateFollowing applies c to the occurrence e and every later one by
+// ending its series before e and continuing it with a new recurring
+// event, which is returned.
+func (r *Resolver) updateFollowing(ctx context.Context, e *model.Event, c eventChange) (*model.Event, error) {
+	series, at, err := r.seriesOf(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	if at.Equal(series.Start) {
+		return r.updateSeries(ctx, series, at, c)
+	}
+	rule, err := rrule.Parse(*series.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	before, err := r.seriesTimes(ctx, series, series.Start, at.Add(-time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	next := occurrence(series, at)
+	next.ID, next.RecurrenceID, next.UID = "", nil, nil
+	following := *rule
+	if rule.Count > 0 {
+		following.Count = rule.Count - len(before)
+	}
+	nextRule := following.String()
+	next.Recurrence = &nextRule
+	series.ExDates, next.ExDates = splitTimes(series.ExDates, at)
+	rule.Count, rule.Until = 0, at.Add(-time.Second)
+	seriesRule := rule.String()
+	series.Recurrence = &seriesRule
+
+	if err := r.applyFields(ctx, next, c); err != nil {
+		return nil, err
+	}
+	delta, err := retime(next, at, c)
+	if err != nil {
+		return nil, err
+	}
+	next.ExDates = shiftTimes(next.ExDates, delta)
+	if c.recurrence != nil && *c.recurrence != "" {
+		next.Recurrence = c.recurrence
+	}
+	if err := r.Store.Events.Create(ctx, next); err != nil {
+		return nil, err
+	}
+	if err := r.Store.Events.Update(ctx, series); err != nil {
+		return nil, err
+	}
+	if err := r.moveOccurrences(ctx, series.ID, next.ID, at, delta); err != nil {
+		return nil, err
+	}
+	if c.recurrence != nil && *c.recurrence == "" {
+		if err := r.setRecurrence(ctx, next, ""); err != nil {
+			return nil, err
+		}
+		if err := r.Store.Events.Update(ctx, next); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// setRecurrence gives e a new recurrence, or makes it a single event at
+// its first occurrence if recurrence is empty. Responses are carried over
+// between a single event and the first occurrence; those to other
+// occurrences are dropped together with the events replacing any. The
+// caller stores e.
+func (r *Resolver) setRecurrence(ctx context.Context, e *model.Event, recurrence string) error {
+	if recurrence != "" {
+		if e.Recurrence == nil {
+			if err := r.moveResponses(ctx, e, nil, &e.Start); err != nil {
+				return err
+			}
+		}
+		e.Recurrence = &recurrence
+		return nil
+	}
+	if e.Recurrence == nil {
+		return nil
+	}
+
+	replaced, err := r.replacements(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	for _, o := range replaced {
+		if err := r.Store.Events.Delete(ctx, o.ID); err != nil {
+			return err
+		}
+	}
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &e.ID})
+	if err != nil {
+		return err
+	}
+	for _, a := range attendees {
+		if a.Occurrence != nil && !a.Occurrence.Equal(e.Start) {
+			if err := r.Store.Attendees.Delete(ctx, a.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := r.moveResponses(ctx, e, &e.Start, nil); err != nil {
+		return err
+	}
+	e.Recurrence, e.ExDates = nil, nil
+	return nil
+}
+
+// moveResponses moves the responses to the occurrence from of e to the
+// occurrence to, a nil occurrence standing for the event itself.
+func (r *Resolver) moveResponses(ctx context.Context, e *model.Event, from, to *time.Time) error {
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &e.ID})
+	if err != nil {
+		return err
+	}
+	for _, a := range attendees {
+		if !sameOccurrence(a.Occurrence, from) {
+			continue
+		}
+		a.Occurrence = to
+		if err := r.Store.Attendees.Update(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameOccurrence(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
+
+// moveOccurrences moves the responses to and replacements of the
+// occurrences of the recurring event fromID starting at or after since to
+// the recurring event toID, shifting them by delta.
+func (r *Resolver) moveOccurrences(ctx context.Context, fromID, toID string, since time.Time, delta time.Duration) error {
+	// Shifting within a series, rows are moved in an order that keeps
+	// them from taking the place of one yet to be moved.
+	later := func(a, b time.Time) bool {
+		if delta > 0 {
+			return a.After(b)
+		}
+		return a.Before(b)
+	}
+
+	events, err := r.Store.Events.List(ctx, storage.EventFilter{SeriesID: &fromID})
+	if err != nil {
+		return err
+	}
+	sort.Slice(events, func(i, j int) bool { return later(*events[i].RecurrenceID, *events[j].RecurrenceID) })
+	for _, o := range events {
+		if o.RecurrenceID.Before(since) {
+			continue
+		}
+		recurrenceID := o.RecurrenceID.Add(delta)
+		o.SeriesID, o.RecurrenceID = &toID, &recurrenceID
+		if err := r.Store.Events.Update(ctx, o); err != nil {
+			return err
+		}
+	}
+
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &fromID})
+	if err != nil {
+		return err
+	}
+	var moved []*model.Attendee
+	for _, a := range attendees {
+		if a.Occurrence != nil && !a.Occurrence.Before(since) {
+			moved = append(moved, a)
+		}
+	}
+	sort.Slice(moved, func(i, j int) bool { return later(*moved[i].Occurrence, *moved[j].Occurrence) })
+	for _, a := range moved {
+		occurrence := a.Occurrence.Add(delta)
+		a.EventID, a.Occurrence = toID, &occurrence
+		if err := r.Store.Attendees.Update(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOccurrence removes the occurrence e starting at at from series.
+// The caller stores series.
+func (r *Resolver) removeOccurrence(ctx context.Context, series, e *model.Event, at time.Time) error {
+	if e.SeriesID != nil {
+		if err := r.Store.Events.Delete(ctx, e.ID); err != nil {
+			return err
+		}
+	} else {
+		attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &series.ID, Occurrence: &at})
+		if err != nil {
+			return err
+		}
+		for _, a := range attendees {
+			if err := r.Store.Attendees.Delete(ctx, a.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if !excluded(series, at) {
+		before, after := splitTimes(series.ExDates, at)
+		series.ExDates = append(append(before, at), after...)
+	}
+	return nil
+}
+
+// truncateSeries ends series before its occurrence at at, dropping the
+// responses to and replacements of later occurrences. The caller stores
+// series.
+func (r *Resolver) truncateSeries(ctx context.Context, series *model.Event, at time.Time) error {
+	rule, err := rrule.Parse(*series.Recurrence)
+	if err != nil {
+		return err
+	}
+	rule.Count, rule.Until = 0, at.Add(-time.Second)
+	recurrence := rule.String()
+	series.Recurrence = &recurrence
+	series.ExDates, _ = splitTimes(series.ExDates, at)
+
+	replaced, err := r.replacements(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+	for _, o := range replaced {
+		if !o.RecurrenceID.Before(at) {
+			if err := r.Store.Events.Delete(ctx, o.ID); err != nil {
+				return err
+			}
+		}
+	}
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{EventID: &series.ID})
+	if err != nil {
+		return err
+	}
+	for _, a := range attendees {
+		if a.Occurrence != nil && !a.Occurrence.Before(at) {
+			if err := r.Store.Attendees.Delete(ctx, a.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/pubsub"
+	"github.com/concertLabs/oaf-server/pkg/relay"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+)
+
+var errUpdateFailed = errors.New("update failed")
+
+// failingAttendees fails to update attendees.
+type failingAttendees struct {
+	storage.AttendeeRepository
+}
+
+func (failingAttendees) Update(ctx context.Context, a *model.Attendee) error {
+	return errUpdateFailed
+}
+
+// failingTransactor runs transactions whose attendees fail to update.
+type failingTransactor struct {
+	storage.Transactor
+}
+
+func (t failingTransactor) Atomic(ctx context.Context, fn func(*storage.Store) error) error {
+	return t.Transactor.Atomic(ctx, func(s *storage.Store) error {
+		tx := *s
+		tx.Attendees = failingAttendees{s.Attendees}
+		return fn(&tx)
+	})
+}
+
+// TestUpdateSeriesAtomic checks that changes to a series failing after
+// some of their writes leave the store as it was.
+func TestUpdateSeriesAtomic(t *testing.T) {
+	start := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+	second := start.AddDate(0, 0, 7)
+	moved := start.Add(time.Hour)
+	tests := []struct {
+		name  string
+		apply model.RecurrenceRange
+		start *time.Time
+	}{
+		{"this occurrence", model.RecurrenceRangeThis, nil},
+		{"following occurrences", model.RecurrenceRangeFollowing, nil},
+		{"all occurrences", model.RecurrenceRangeAll, &moved},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		store := memory.New()
+		u := &model.User{Username: "alice", Email: "alice@example.org"}
+		if err := store.Users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		o := &model.Organization{Name: "Orchestra", Timezone: "UTC"}
+		if err := store.Organizations.Create(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		weekly := "FREQ=WEEKLY;COUNT=4"
+		series := &model.Event{Name: "Rehearsal", Start: start, CreatorID: u.ID, OrganizationID: &o.ID, Recurrence: &weekly}
+		if err := store.Events.Create(ctx, series); err != nil {
+			t.Fatal(err)
+		}
+		at := second
+		a := &model.Attendee{UserID: u.ID, EventID: series.ID, Occurrence: &at, Commitment: model.CommitmentYes}
+		if err := store.Attendees.Create(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+		events, attendees := storeContents(t, store)
+
+		store.Transactor = failingTransactor{store.Transactor}
+		r := &mutationResolver{&Resolver{Store: store, Bus: pubsub.New()}}
+		name := "Sectional"
+		id := relay.ToGlobalID("Event", model.Event{ID: series.ID, RecurrenceID: &at}.Key())
+		apply := tt.apply
+		if _, err := r.UpdateEvent(ctx, id, &name, nil, nil, tt.start, nil, nil, nil, &apply); !errors.Is(err, errUpdateFailed) {
+			t.Errorf("%s: UpdateEvent = %v, want %v", tt.name, err, errUpdateFailed)
+		}
+		if gotEvents, gotAttendees := storeContents(t, store); !reflect.DeepEqual(gotEvents, events) || !reflect.DeepEqual(gotAttendees, attendees) {
+			t.Errorf("%s: store changed to events %v and attendees %v", tt.name, gotEvents, gotAttendees)
+		}
+	}
+}
+
+func storeContents(t *testing.T, store *storage.Store) ([]*model.Event, []*model.Attendee) {
+	t.Helper()
+	ctx := context.Background()
+	events, err := store.Events.List(ctx, storage.EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attendees, err := store.Attendees.List(ctx, storage.AttendeeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events, attendees
+}
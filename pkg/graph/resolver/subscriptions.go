@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+// Each topic on the bus is about a single event. The messages sent to a
+// topic are of the type the matching subscription delivers.
+
+func eventTopic(eventID string) string   { return "event/" + eventID }
+func commentTopic(eventID string) string { return "comment/" + eventID }
+
+// attendeeTopic is the topic of the responses to an event, or to the
+// occurrence of a recurring event starting at occurrence if it is set.
+func attendeeTopic(eventID string, occurrence *time.Time) string {
+	if occurrence == nil {
+		return "attendee/" + eventID
+	}
+	return "attendee/" + eventID + "@" + occurrence.UTC().Format(model.RecurrenceIDFormat)
+}
+
+// publishEvent publishes e to its own topic and, if it replaces an
+// occurrence, to that of its series.
+func (r *Resolver) publishEvent(e *model.Event) {
+	r.Bus.Publish(eventTopic(e.ID), e)
+	if e.SeriesID != nil {
+		r.Bus.Publish(eventTopic(*e.SeriesID), e)
+	}
+}
+
+// publishAttendee publishes a change of a to the topic of its event and,
+// if it is a response to an occurrence, to that of the occurrence.
+func (r *Resolver) publishAttendee(kind model.ChangeKind, a *model.Attendee) {
+	change := &model.AttendeeChange{Kind: kind, Attendee: a}
+	r.Bus.Publish(attendeeTopic(a.EventID, nil), change)
+	if a.Occurrence != nil {
+		r.Bus.Publish(attendeeTopic(a.EventID, a.Occurrence), change)
+	}
+}
+
+func (r *Resolver) publishComment(c *model.Comment) {
+	r.Bus.Publish(commentTopic(c.EventID), c)
+}
+
+// subscribableEvent decodes the event argument of a subscription and
+// makes sure the event exists, so that clients do not wait for updates
+// that can never come. For an occurrence of a recurring event, it returns
+// the ID of the series and the start of the occurrence.
+func (r *Resolver) subscribableEvent(ctx context.Context, gid string) (string, *time.Time, error) {
+	e, err := r.loadEvent(ctx, gid)
+	if err != nil {
+		return "", nil, err
+	}
+	if e.IsOccurrence() {
+		return e.ID, e.RecurrenceID, nil
+	}
+	return e.ID, nil, nil
+}
@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/pubsub"
+)
+
+func TestPublishAttendee(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &Resolver{Bus: pubsub.New()}
+	first := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 7)
+	series := r.Bus.Subscribe(ctx, attendeeTopic("series", nil))
+	atFirst := r.Bus.Subscribe(ctx, attendeeTopic("series", &first))
+	atSecond := r.Bus.Subscribe(ctx, attendeeTopic("series", &second))
+	single := r.Bus.Subscribe(ctx, attendeeTopic("single", nil))
+
+	// Occurrences given in another time zone are the same occurrence.
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inBerlin := first.In(berlin)
+	r.publishAttendee(model.ChangeKindCreated, &model.Attendee{ID: "1", EventID: "series", Occurrence: &inBerlin})
+	r.publishAttendee(model.ChangeKindCreated, &model.Attendee{ID: "2", EventID: "single"})
+
+	tests := []struct {
+		name string
+		ch   <-chan interface{}
+		want []string
+	}{
+		{"series", series, []string{"1"}},
+		{"first occurrence", atFirst, []string{"1"}},
+		{"second occurrence", atSecond, nil},
+		{"single event", single, []string{"2"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for len(tt.ch) > 0 {
+			got = append(got, (<-tt.ch).(*model.AttendeeChange).Attendee.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: received %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
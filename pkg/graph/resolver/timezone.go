@@ -0,0 +1,54 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+// loadLocation resolves an IANA time zone name such as Europe/Berlin.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, apperr.Errorf(apperr.Validation, "unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, apperr.Errorf(apperr.Validation, "unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// eventLocation returns the location the times of e are shown in unless
+// the client asks for another one: the time zone of its organization, or
+// UTC for events without one.
+func (r *Resolver) eventLocation(ctx context.Context, e *model.Event) (*time.Location, error) {
+	if e.OrganizationID == nil {
+		return time.UTC, nil
+	}
+	o, err := r.organization(ctx, *e.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	return loadLocation(o.Timezone)
+}
+
+// eventTime returns t, a time of e, in the location named by timezone or
+// else in the default location of e.
+func (r *Resolver) eventTime(ctx context.Context, e *model.Event, t time.Time, timezone *string) (*time.Time, error) {
+	var (
+		loc *time.Location
+		err error
+	)
+	if timezone != nil {
+		loc, err = loadLocation(*timezone)
+	} else {
+		loc, err = r.eventLocation(ctx, e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t = t.In(loc)
+	return &t, nil
+}
@@ -0,0 +1,285 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Validation returns the extension checking the arguments of fields
+// against the @constraint directives of the schema and the checks of
+// their input types before the fields are resolved, and after @hasRight
+// authorized the caller. All violations are reported in one VALIDATION
+// error.
+func (r *Resolver) Validation() graphql.HandlerExtension {
+	return &validation{
+		checks: map[string]inputCheck{
+			"NewUser":  r.checkNewUser,
+			"NewEvent": checkNewEvent,
+		},
+	}
+}
+
+// inputCheck validates the unmarshalled value of an input object found
+// at path in the arguments, for what cannot be declared in the schema.
+type inputCheck func(ctx context.Context, path string, v interface{}) ([]apperr.FieldError, error)
+
+type validation struct {
+	// checks holds the checks of the input types of arguments by their
+	// name. Input objects nested in arguments are not checked.
+	checks      map[string]inputCheck
+	schema      *ast.Schema
+	constraints map[*ast.Directive]*constraint
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = &validation{}
+
+func (v *validation) ExtensionName() string {
+	return "Validation"
+}
+
+// Validate parses the @constraint directives of the schema.
+func (v *validation) Validate(es graphql.ExecutableSchema) error {
+	schema := es.Schema()
+	constraints := map[*ast.Directive]*constraint{}
+	add := func(name string, typ *ast.Type, directives ast.DirectiveList) error {
+		d := directives.ForName("constraint")
+		if d == nil {
+			return nil
+		}
+		if typ.Name() != "String" {
+			return fmt.Errorf("@constraint on %s: only strings can be constrained", name)
+		}
+		c, err := parseConstraint(d)
+		if err != nil {
+			return fmt.Errorf("@constraint on %s: %w", name, err)
+		}
+		constraints[d] = c
+		return nil
+	}
+	for _, def := range schema.Types {
+		for _, f := range def.Fields {
+			if def.Kind == ast.InputObject {
+				if err := add(def.Name+"."+f.Name, f.Type, f.Directives); err != nil {
+					return err
+				}
+			}
+			for _, arg := range f.Arguments {
+				if err := add(def.Name+"."+f.Name+"("+arg.Name+")", arg.Type, arg.Directives); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	v.schema = schema
+	v.constraints = constraints
+	return nil
+}
+
+func (v *validation) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Field.Definition == nil || len(fc.Field.Definition.Arguments) == 0 {
+		return next(ctx)
+	}
+	if fc.Field.Definition.Directives.ForName("hasRight") != nil {
+		// Callers lacking the right learn nothing about the arguments,
+		// such as whether a name is taken: @hasRight validates them
+		// once they pass, see validateArgs.
+		return next(context.WithValue(ctx, pendingKey{}, &pending{v, fc}))
+	}
+	if err := v.check(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
+
+type pendingKey struct{}
+
+// pending is the validation of a field left to its @hasRight directive.
+type pending struct {
+	v     *validation
+	field *graphql.FieldContext
+}
+
+// validateArgs validates the arguments of the current field if Validation
+// left that to it.
+func validateArgs(ctx context.Context) error {
+	p, ok := ctx.Value(pendingKey{}).(*pending)
+	if !ok || p.field != graphql.GetFieldContext(ctx) {
+		return nil
+	}
+	return p.v.check(ctx)
+}
+
+// check validates the arguments of the current field.
+func (v *validation) check(ctx context.Context) error {
+	fc := graphql.GetFieldContext(ctx)
+	var violations []apperr.FieldError
+	args := rawArgs(ctx)
+	for _, arg := range fc.Field.Definition.Arguments {
+		v.checkValue(&violations, arg.Name, arg.Type, arg.Directives, args[arg.Name])
+		if check, ok := v.checks[arg.Type.Name()]; ok && fc.Args[arg.Name] != nil {
+			found, err := check(ctx, arg.Name, fc.Args[arg.Name])
+			if err != nil {
+				return err
+			}
+			violations = append(violations, found...)
+		}
+	}
+	if len(violations) > 0 {
+		return apperr.Invalid(violations...)
+	}
+	return nil
+}
+
+// checkValue appends the violations of the constraints on the raw value
+// at path, and on the fields of input objects within it, to violations.
+func (v *validation) checkValue(violations *[]apperr.FieldError, path string, typ *ast.Type, directives ast.DirectiveList, value interface{}) {
+	if value == nil {
+		return
+	}
+	if typ.Elem != nil {
+		// Single values are accepted for lists.
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		for i, item := range list {
+			v.checkValue(violations, path+"."+strconv.Itoa(i), typ.Elem, directives, item)
+		}
+		return
+	}
+
+	if c := v.constraints[directives.ForName("constraint")]; c != nil {
+		if s, ok := value.(string); ok {
+			for _, msg := range c.check(s) {
+				*violations = append(*violations, apperr.FieldError{Field: path, Message: msg})
+			}
+		}
+	}
+	def := v.schema.Types[typ.Name()]
+	fields, ok := value.(map[string]interface{})
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return
+	}
+	for _, f := range def.Fields {
+		v.checkValue(violations, path+"."+f.Name, f.Type, f.Directives, fields[f.Name])
+	}
+}
+
+// constraint holds the arguments of a @constraint directive.
+type constraint struct {
+	minLength, maxLength int
+	notBlank             bool
+	pattern              *regexp.Regexp
+	format               string
+}
+
+// format is a format of @constraint.
+type format struct {
+	// what describes the values of the format.
+	what  string
+	valid func(string) bool
+}
+
+var formats = map[string]format{
+	"EMAIL": {"an email address", func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	}},
+}
+
+func parseConstraint(d *ast.Directive) (*constraint, error) {
+	c := &constraint{}
+	for _, arg := range d.Arguments {
+		raw := arg.Value.Raw
+		var err error
+		switch arg.Name {
+		case "minLength":
+			c.minLength, err = strconv.Atoi(raw)
+		case "maxLength":
+			c.maxLength, err = strconv.Atoi(raw)
+		case "notBlank":
+			c.notBlank = raw == "true"
+		case "pattern":
+			c.pattern, err = regexp.Compile(raw)
+		case "format":
+			if _, ok := formats[raw]; !ok {
+				err = fmt.Errorf("unknown format %q", raw)
+			}
+			c.format = raw
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// check returns what is wrong with s.
+func (c *constraint) check(s string) []string {
+	var msgs []string
+	n := utf8.RuneCountInString(s)
+	if c.minLength > 0 && n < c.minLength {
+		msgs = append(msgs, fmt.Sprintf("must be at least %d characters long", c.minLength))
+	}
+	if c.maxLength > 0 && n > c.maxLength {
+		msgs = append(msgs, fmt.Sprintf("must be at most %d characters long", c.maxLength))
+	}
+	if c.notBlank && strings.TrimSpace(s) == "" {
+		msgs = append(msgs, "must not be blank")
+	}
+	if c.pattern != nil && !c.pattern.MatchString(s) {
+		msgs = append(msgs, fmt.Sprintf("must match %s", c.pattern))
+	}
+	if f, ok := formats[c.format]; ok && !f.valid(s) {
+		msgs = append(msgs, "must be "+f.what)
+	}
+	return msgs
+}
+
+// checkNewUser makes sure the username is not taken.
+func (r *Resolver) checkNewUser(ctx context.Context, path string, v interface{}) ([]apperr.FieldError, error) {
+	in, ok := v.(model.NewUser)
+	if !ok {
+		return nil, nil
+	}
+	_, err := r.Store.Users.GetByUsername(ctx, in.Username)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []apperr.FieldError{{Field: path + ".username", Message: "is taken"}}, nil
+}
+
+// checkNewEvent makes sure the event ends after it starts.
+func checkNewEvent(ctx context.Context, path string, v interface{}) ([]apperr.FieldError, error) {
+	in, ok := v.(model.NewEvent)
+	if !ok || endsAfterStart(in.Start, in.End) {
+		return nil, nil
+	}
+	return []apperr.FieldError{{Field: path + ".end", Message: "must be after start"}}, nil
+}
+
+// endsAfterStart reports whether an event starting at start ends after
+// it, as events with an end must, however they are created or changed.
+func endsAfterStart(start time.Time, end *time.Time) bool {
+	return end == nil || end.After(start)
+}
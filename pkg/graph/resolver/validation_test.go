@@ -0,0 +1,190 @@
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]string
+		s    string
+		want []string
+	}{
+		{"min length", map[string]string{"minLength": "3"}, "ab", []string{"must be at least 3 characters long"}},
+		{"min length in characters", map[string]string{"minLength": "3"}, "äöü", nil},
+		{"max length", map[string]string{"maxLength": "3"}, "abcd", []string{"must be at most 3 characters long"}},
+		{"max length in characters", map[string]string{"maxLength": "3"}, "äöü", nil},
+		{"not blank", map[string]string{"notBlank": "true"}, " \t", []string{"must not be blank"}},
+		{"not blank with text", map[string]string{"notBlank": "true"}, " a ", nil},
+		{"pattern", map[string]string{"pattern": "^[a-z]+$"}, "a1", []string{"must match ^[a-z]+$"}},
+		{"unanchored pattern", map[string]string{"pattern": "[0-9]"}, "a1", nil},
+		{"email", map[string]string{"format": "EMAIL"}, "alice@example.org", nil},
+		{"email with a name", map[string]string{"format": "EMAIL"}, "Alice <alice@example.org>", []string{"must be an email address"}},
+		{"not an email", map[string]string{"format": "EMAIL"}, "alice", []string{"must be an email address"}},
+		{
+			"several",
+			map[string]string{"minLength": "3", "pattern": "^[a-z]"},
+			"1",
+			[]string{"must be at least 3 characters long", "must match ^[a-z]"},
+		},
+	}
+	for _, tt := range tests {
+		d := &ast.Directive{Name: "constraint"}
+		for name, raw := range tt.args {
+			d.Arguments = append(d.Arguments, &ast.Argument{Name: name, Value: &ast.Value{Raw: raw}})
+		}
+		c, err := parseConstraint(d)
+		if err != nil {
+			t.Errorf("%s: parseConstraint = %v", tt.name, err)
+			continue
+		}
+		if got := c.check(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: check(%q) = %q, want %q", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"minLength": "three",
+		"maxLength": "",
+		"pattern":   "(",
+		"format":    "PHONE",
+	} {
+		d := &ast.Directive{Name: "constraint", Arguments: ast.ArgumentList{{Name: name, Value: &ast.Value{Raw: raw}}}}
+		if _, err := parseConstraint(d); err == nil {
+			t.Errorf("parseConstraint(%s: %q) succeeded", name, raw)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	newUser := func(username, email string) map[string]interface{} {
+		return map[string]interface{}{"user": map[string]interface{}{"username": username, "password": "pw", "email": email}}
+	}
+	const createUser = `mutation($user: NewUser!) { createUser(user: $user) { id } }`
+	const updateOrganization = `mutation($id: ID!, $name: String) { updateOrganization(id: $id, name: $name) { id } }`
+	const createEvent = `mutation($event: NewEvent!) { createEvent(event: $event) { id } }`
+	newEvent := func(f *fixture, start, end string) map[string]interface{} {
+		return map[string]interface{}{"event": map[string]interface{}{
+			"organization": f.orchestra,
+			"name":         "Concert",
+			"start":        start,
+			"end":          end,
+		}}
+	}
+
+	tests := []struct {
+		name  string
+		user  func(f *fixture) *model.User
+		query string
+		vars  func(f *fixture) map[string]interface{}
+		// want is the code of the error, fields the fields a
+		// VALIDATION error is about.
+		want   apperr.Code
+		fields []string
+	}{
+		{
+			"valid input",
+			func(f *fixture) *model.User { return nil },
+			createUser,
+			func(f *fixture) map[string]interface{} { return newUser("dave", "dave@example.org") },
+			"", nil,
+		},
+		{
+			"every violation",
+			func(f *fixture) *model.User { return nil },
+			createUser,
+			func(f *fixture) map[string]interface{} { return newUser("-d", "dave") },
+			apperr.Validation, []string{"user.username", "user.username", "user.email"},
+		},
+		{
+			"taken username",
+			func(f *fixture) *model.User { return nil },
+			createUser,
+			func(f *fixture) map[string]interface{} { return newUser("alice", "dave@example.org") },
+			apperr.Validation, []string{"user.username"},
+		},
+		{
+			"argument",
+			func(f *fixture) *model.User { return f.alice },
+			`mutation($id: ID!) { updateUser(id: $id, email: "alice") { id } }`,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": "unused"} },
+			apperr.Validation, []string{"email"},
+		},
+		{
+			"omitted optional argument",
+			func(f *fixture) *model.User { return f.alice },
+			updateOrganization,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra} },
+			"", nil,
+		},
+		{
+			"argument after @hasRight",
+			func(f *fixture) *model.User { return f.alice },
+			updateOrganization,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra, "name": " "} },
+			apperr.Validation, []string{"name"},
+		},
+		{
+			"argument without the right",
+			func(f *fixture) *model.User { return f.bob },
+			updateOrganization,
+			func(f *fixture) map[string]interface{} { return map[string]interface{}{"id": f.orchestra, "name": " "} },
+			apperr.Forbidden, nil,
+		},
+		{
+			"input check after @hasRight",
+			func(f *fixture) *model.User { return f.alice },
+			createEvent,
+			func(f *fixture) map[string]interface{} {
+				return newEvent(f, "2030-01-07T18:00:00Z", "2030-01-07T18:00:00Z")
+			},
+			apperr.Validation, []string{"event.end"},
+		},
+		{
+			"input check without the right",
+			func(f *fixture) *model.User { return f.bob },
+			createEvent,
+			func(f *fixture) map[string]interface{} {
+				return newEvent(f, "2030-01-07T18:00:00Z", "2030-01-07T17:00:00Z")
+			},
+			apperr.Forbidden, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			err := s.do(t, tt.user(s.fixture), tt.query, tt.vars(s.fixture))
+			var got apperr.Code
+			if err != nil {
+				got = apperr.CodeOf(err)
+			}
+			if got != tt.want {
+				t.Fatalf("error %v with code %s, want %s", err, got, tt.want)
+			}
+			var e *apperr.Error
+			if tt.fields == nil || !errors.As(err, &e) {
+				if tt.fields != nil {
+					t.Errorf("error %v, want an error about %s", err, strings.Join(tt.fields, ", "))
+				}
+				return
+			}
+			var fields []string
+			for _, f := range e.Fields {
+				fields = append(fields, f.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("error about %s, want %s", strings.Join(fields, ", "), strings.Join(tt.fields, ", "))
+			}
+		})
+	}
+}
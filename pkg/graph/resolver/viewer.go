@@ -0,0 +1,227 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+// userOrganizations returns the organizations the user is a member of a
+// section in, ordered by name.
+func (r *Resolver) userOrganizations(ctx context.Context, userID string) ([]*model.Organization, error) {
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := r.sections(ctx, memberSectionIDs(members))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var ids []string
+	for _, s := range sections {
+		if !seen[s.OrganizationID] {
+			seen[s.OrganizationID] = true
+			ids = append(ids, s.OrganizationID)
+		}
+	}
+	orgs, err := r.organizations(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs, nil
+}
+
+// upcomingHorizon bounds how far past now or their start recurring events
+// are expanded when looking for their next occurrence.
+const upcomingHorizon = 366 * 24 * time.Hour
+
+// upcomingEvents returns the events starting after now that the user is
+// expected at or has responded to, except those they declined, soonest
+// first. Of a recurring event only the next occurrence not declined is
+// returned.
+func (r *Resolver) upcomingEvents(ctx context.Context, userID string, now time.Time) ([]*model.Event, error) {
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var events []*model.Event
+	add := func(e *model.Event) {
+		if seen[e.Key()] || !e.Start.After(now) {
+			return
+		}
+		seen[e.Key()] = true
+		events = append(events, e)
+	}
+
+	var responded []*model.Attendee
+	for _, a := range attendees {
+		if a.Commitment == model.CommitmentNo {
+			seen[attendeeKey(a)] = true
+			continue
+		}
+		responded = append(responded, a)
+	}
+
+	sections, orgs, err := r.memberSections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for orgID := range orgs {
+		orgID := orgID
+		list, err := r.Store.Events.List(ctx, storage.EventFilter{OrganizationID: &orgID, Start: &now})
+		if err != nil {
+			return nil, err
+		}
+		replaced, err := r.replacementsOf(ctx, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list {
+			if !expected(e, sections) {
+				continue
+			}
+			if e.Recurrence == nil {
+				add(e)
+				continue
+			}
+			from := now
+			if e.Start.After(now) {
+				from = e.Start
+			}
+			occurrences, err := r.occurrences(ctx, e, replaced[e.ID], now, from.Add(upcomingHorizon))
+			if err != nil {
+				return nil, err
+			}
+			for _, o := range occurrences {
+				if o.Start.After(now) && !seen[o.Key()] {
+					add(o)
+					break
+				}
+			}
+		}
+	}
+	r.prefetchEvents(ctx, responded)
+	for _, a := range responded {
+		e, err := r.attendeeEvent(ctx, a)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(e)
+	}
+
+	sortEvents(events)
+	return events, nil
+}
+
+// personalEvents returns the events and occurrences starting within
+// [from, to] that the user is expected at or has responded to, except
+// those they declined, ordered by start.
+func (r *Resolver) personalEvents(ctx context.Context, userID string, from, to time.Time) ([]*model.Event, error) {
+	attendees, err := r.Store.Attendees.List(ctx, storage.AttendeeFilter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var events []*model.Event
+	add := func(e *model.Event) {
+		if seen[e.Key()] || e.Start.Before(from) || e.Start.After(to) {
+			return
+		}
+		seen[e.Key()] = true
+		events = append(events, e)
+	}
+
+	var responded []*model.Attendee
+	for _, a := range attendees {
+		if a.Commitment == model.CommitmentNo {
+			seen[attendeeKey(a)] = true
+			continue
+		}
+		responded = append(responded, a)
+	}
+
+	sections, orgs, err := r.memberSections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for orgID := range orgs {
+		orgID := orgID
+		list, err := r.listEvents(ctx, storage.EventFilter{OrganizationID: &orgID, Start: &from, End: &to})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list {
+			if expected(e, sections) {
+				add(e)
+			}
+		}
+	}
+	for _, a := range responded {
+		e, err := r.attendeeEvent(ctx, a)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		add(e)
+	}
+
+	sortEvents(events)
+	return events, nil
+}
+
+// memberSections returns the IDs of the sections the user is a member of
+// and of their organizations.
+func (r *Resolver) memberSections(ctx context.Context, userID string) (map[string]bool, map[string]bool, error) {
+	members, err := r.Store.Members.List(ctx, storage.MemberFilter{UserID: &userID})
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := r.sections(ctx, memberSectionIDs(members))
+	if err != nil {
+		return nil, nil, err
+	}
+	sections := map[string]bool{}
+	orgs := map[string]bool{}
+	for _, s := range list {
+		sections[s.ID] = true
+		orgs[s.OrganizationID] = true
+	}
+	return sections, orgs, nil
+}
+
+func memberSectionIDs(members []*model.Member) []string {
+	ids := make([]string, len(members))
+	for i, m := range members {
+		ids[i] = m.SectionID
+	}
+	return ids
+}
+
+// expected reports whether members of the given sections are expected at
+// e, which must belong to the organization of one of them.
+func expected(e *model.Event, sections map[string]bool) bool {
+	if len(e.SectionIDs) == 0 {
+		return true
+	}
+	for _, id := range e.SectionIDs {
+		if sections[id] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Package ical reads and writes calendars in the iCalendar format of
+// RFC 5545, as served to calendar applications subscribing to event feeds
+// and imported from the schedules they export.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of iCalendar data.
+const ContentType = "text/calendar; charset=utf-8"
+
+// TimeFormat is the layout of times in UTC.
+const TimeFormat = "20060102T150405Z"
+
+// prodID identifies oaf-server as the producer of calendars.
+const prodID = "-//concertLabs//oaf-server//EN"
+
+// Calendar is a VCALENDAR holding events.
+type Calendar struct {
+	// Name is shown by calendar applications for the subscribed calendar.
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. Times are written in UTC.
+type Event struct {
+	UID string
+	// Stamp is when the event was last rendered, the DTSTAMP.
+	Stamp       time.Time
+	Start       time.Time
+	End         *time.Time
+	Summary     string
+	Description string
+	Location    string
+	// Reminder is how long before the start a reminder is shown, none if
+	// zero.
+	Reminder time.Duration
+	// Recurrence is the RRULE of a recurring event.
+	Recurrence string
+	// ExDates are the starts of occurrences removed from a recurring
+	// event.
+	ExDates []time.Time
+	// RecurrenceID is set on events replacing an occurrence of the
+	// recurring event with the same UID, to the original start of that
+	// occurrence.
+	RecurrenceID *time.Time
+	// Cancelled marks events with STATUS:CANCELLED.
+	Cancelled bool
+	// Invalid, if set, tells why the event cannot be read, such as a time
+	// in an unknown time zone. The fields it concerns are left zero.
+	Invalid string
+}
+
+// Write writes c to w.
+func Write(w io.Writer, c *Calendar) error {
+	cw := &writer{w: bufio.NewWriter(w)}
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", prodID)
+	cw.line("CALSCALE", "GREGORIAN")
+	cw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, e := range c.Events {
+		cw.event(&e)
+	}
+	cw.line("END", "VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *writer) event(e *Event) {
+	cw.line("BEGIN", "VEVENT")
+	cw.line("UID", escape(e.UID))
+	cw.line("DTSTAMP", e.Stamp.UTC().Format(TimeFormat))
+	cw.line("DTSTART", e.Start.UTC().Format(TimeFormat))
+	if e.End != nil {
+		cw.line("DTEND", e.End.UTC().Format(TimeFormat))
+	}
+	if e.RecurrenceID != nil {
+		cw.line("RECURRENCE-ID", e.RecurrenceID.UTC().Format(TimeFormat))
+	}
+	if e.Recurrence != "" {
+		cw.line("RRULE", e.Recurrence)
+	}
+	if len(e.ExDates) > 0 {
+		exdates := make([]string, len(e.ExDates))
+		for i, t := range e.ExDates {
+			exdates[i] = t.UTC().Format(TimeFormat)
+		}
+		cw.line("EXDATE", strings.Join(exdates, ","))
+	}
+	if e.Cancelled {
+		cw.line("STATUS", "CANCELLED")
+	}
+	cw.line("SUMMARY", escape(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION", escape(e.Description))
+	}
+	if e.Location != "" {
+		cw.line("LOCATION", escape(e.Location))
+	}
+	if e.Reminder > 0 {
+		cw.line("BEGIN", "VALARM")
+		cw.line("ACTION", "DISPLAY")
+		cw.line("TRIGGER", "-"+duration(e.Reminder))
+		cw.line("DESCRIPTION", escape(e.Summary))
+		cw.line("END", "VALARM")
+	}
+	cw.line("END", "VEVENT")
+}
+
+// line writes a content line, folding it into lines of at most 75 octets
+// without splitting UTF-8 sequences.
+func (cw *writer) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+	s := name + ":" + value
+	max := 75
+	for len(s) > max {
+		i := max
+		for s[i]&0xC0 == 0x80 {
+			i--
+		}
+		if _, cw.err = cw.w.WriteString(s[:i] + "\r\n "); cw.err != nil {
+			return
+		}
+		s = s[i:]
+		// The space starting continuation lines counts towards their
+		// length.
+		max = 74
+	}
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// duration formats d, rounded to seconds, as a positive DURATION value.
+func duration(d time.Duration) string {
+	s := int64(d.Round(time.Second) / time.Second)
+	var b strings.Builder
+	b.WriteString("P")
+	if days := s / 86400; days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		s %= 86400
+	}
+	if s > 0 {
+		b.WriteString("T")
+		if h := s / 3600; h > 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m := s % 3600 / 60; m > 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if sec := s % 60; sec > 0 {
+			fmt.Fprintf(&b, "%dS", sec)
+		}
+	}
+	if b.Len() == 1 {
+		b.WriteString("T0S")
+	}
+	return b.String()
+}
@@ -0,0 +1,336 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestRoundTrip(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse(TimeFormat, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	in := &Calendar{
+		Name: "Orchestra; Strings, Winds",
+		Events: []Event{
+			{
+				UID:         "1@oaf",
+				Stamp:       at("20300101T120000Z"),
+				Start:       at("20300107T180000Z"),
+				End:         ptr(at("20300107T210000Z")),
+				Summary:     `Tutti \ rehearsal; bring stands, please`,
+				Description: "First line\nsecond line with ümlauts and 🎻 " + strings.Repeat("long ", 40),
+				Location:    "Hall 2",
+				Recurrence:  "FREQ=WEEKLY;BYDAY=MO",
+				ExDates:     []time.Time{at("20300114T180000Z"), at("20300121T180000Z")},
+			},
+			{
+				UID:          "1@oaf",
+				Stamp:        at("20300101T120000Z"),
+				Start:        at("20300128T190000Z"),
+				End:          ptr(at("20300128T220000Z")),
+				Summary:      "Tutti rehearsal, later",
+				RecurrenceID: ptr(at("20300128T180000Z")),
+			},
+			{
+				UID:       "2@oaf",
+				Stamp:     at("20300101T120000Z"),
+				Start:     at("20300301T180000Z"),
+				Summary:   "Concert",
+				Cancelled: true,
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Parse(&buf, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Parse(Write(c)) = %+v, want %+v", out, in)
+	}
+}
+
+func TestWriteFolding(t *testing.T) {
+	tests := []string{
+		strings.Repeat("a", 200),
+		strings.Repeat("ü", 100),
+		strings.Repeat("🎻", 50),
+		"x" + strings.Repeat("€", 70),
+	}
+	for _, summary := range tests {
+		var buf bytes.Buffer
+		c := &Calendar{Events: []Event{{UID: "1", Summary: summary}}}
+		if err := Write(&buf, c); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+			if len(line) > 75 {
+				t.Errorf("line of %d octets: %q", len(line), line)
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("line splits a UTF-8 sequence: %q", line)
+			}
+		}
+		unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+		if !strings.Contains(unfolded, "\r\nSUMMARY:"+summary+"\r\n") {
+			t.Errorf("unfolded calendar lacks the summary %q:\n%s", summary, unfolded)
+		}
+	}
+}
+
+func TestWriteReminder(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Calendar{Events: []Event{{UID: "1", Summary: "Concert", Reminder: 90 * time.Minute}}}
+	if err := Write(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT1H30M\r\nDESCRIPTION:Concert\r\nEND:VALARM\r\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("calendar lacks the alarm %q:\n%s", want, buf.String())
+	}
+}
+
+// calendar wraps the lines of a VEVENT in a VCALENDAR.
+func calendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\n" +
+		strings.Join(lines, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	tests := []struct {
+		name string
+		in   string
+		want Event
+	}{
+		{
+			name: "utc",
+			in:   calendar("UID:a", "DTSTART:20300107T180000Z", "DTEND:20300107T210000Z", "SUMMARY:Tutti"),
+			want: Event{
+				UID:     "a",
+				Start:   time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC),
+				End:     ptr(time.Date(2030, 1, 7, 21, 0, 0, 0, time.UTC)),
+				Summary: "Tutti",
+			},
+		},
+		{
+			name: "floating times are in the given location",
+			in:   calendar("DTSTART:20300107T190000", "DTEND:20300107T220000"),
+			want: Event{
+				Start: time.Date(2030, 1, 7, 19, 0, 0, 0, berlin),
+				End:   ptr(time.Date(2030, 1, 7, 22, 0, 0, 0, berlin)),
+			},
+		},
+		{
+			name: "tzid",
+			in:   calendar("DTSTART;TZID=America/New_York:20300707T190000", `DTEND;TZID="America/New_York":20300707T220000`),
+			want: Event{
+				Start: time.Date(2030, 7, 7, 19, 0, 0, 0, newYork),
+				End:   ptr(time.Date(2030, 7, 7, 22, 0, 0, 0, newYork)),
+			},
+		},
+		{
+			name: "tzid with a leading slash",
+			in:   calendar("DTSTART;TZID=/America/New_York:20300707T190000"),
+			want: Event{Start: time.Date(2030, 7, 7, 19, 0, 0, 0, newYork)},
+		},
+		{
+			name: "unknown tzid",
+			in:   calendar("UID:a", "DTSTART;TZID=Mars/Olympus:20300707T190000", "DTEND:20300707T220000Z", "SUMMARY:Tutti"),
+			want: Event{
+				UID:     "a",
+				End:     ptr(time.Date(2030, 7, 7, 22, 0, 0, 0, time.UTC)),
+				Summary: "Tutti",
+				Invalid: `DTSTART: unknown time zone "Mars/Olympus"`,
+			},
+		},
+		{
+			name: "first invalid value is kept",
+			in:   calendar("DTSTART:20300707T190000Z", "DURATION:soon", "RECURRENCE-ID:never"),
+			want: Event{
+				Start:   time.Date(2030, 7, 7, 19, 0, 0, 0, time.UTC),
+				Invalid: `DURATION: malformed duration "soon"`,
+			},
+		},
+		{
+			name: "date lasts the day",
+			in:   calendar("DTSTART;VALUE=DATE:20300707"),
+			want: Event{
+				Start: time.Date(2030, 7, 7, 0, 0, 0, 0, berlin),
+				End:   ptr(time.Date(2030, 7, 8, 0, 0, 0, 0, berlin)),
+			},
+		},
+		{
+			name: "duration",
+			in:   calendar("DTSTART:20300707T190000Z", "DURATION:PT2H30M"),
+			want: Event{
+				Start: time.Date(2030, 7, 7, 19, 0, 0, 0, time.UTC),
+				End:   ptr(time.Date(2030, 7, 7, 21, 30, 0, 0, time.UTC)),
+			},
+		},
+		{
+			name: "dtend wins over duration",
+			in:   calendar("DTSTART:20300707T190000Z", "DURATION:P1D", "DTEND:20300707T200000Z"),
+			want: Event{
+				Start: time.Date(2030, 7, 7, 19, 0, 0, 0, time.UTC),
+				End:   ptr(time.Date(2030, 7, 7, 20, 0, 0, 0, time.UTC)),
+			},
+		},
+		{
+			name: "folded lines and escapes",
+			in:   calendar("SUMMARY:Tutti\\, then", "  sectionals\\; bring\\nstands", "\t and music \\\\ pencils"),
+			want: Event{Summary: "Tutti, then sectionals; bring\nstands and music \\ pencils"},
+		},
+		{
+			name: "exdates are sorted",
+			in:   calendar("RRULE:FREQ=DAILY", "EXDATE:20300109T190000Z,20300108T190000Z", "EXDATE;TZID=Europe/Berlin:20300107T200000"),
+			want: Event{
+				Recurrence: "FREQ=DAILY",
+				ExDates: []time.Time{
+					time.Date(2030, 1, 7, 20, 0, 0, 0, berlin),
+					time.Date(2030, 1, 8, 19, 0, 0, 0, time.UTC),
+					time.Date(2030, 1, 9, 19, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "second rrule",
+			in:   calendar("RRULE:FREQ=DAILY", "RRULE:FREQ=WEEKLY"),
+			want: Event{
+				Recurrence: "FREQ=DAILY",
+				Invalid:    "RRULE: events with more than one RRULE are not supported",
+			},
+		},
+		{
+			name: "cancelled",
+			in:   calendar("status:cancelled"),
+			want: Event{Cancelled: true},
+		},
+		{
+			name: "alarms and unknown properties are skipped",
+			in:   calendar("SUMMARY:Tutti", "X-APPLE-TRAVEL-TIME:PT1H", "BEGIN:VALARM", "SUMMARY:Alarm", "TRIGGER:-PT1H", "END:VALARM"),
+			want: Event{Summary: "Tutti"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(strings.NewReader(tt.in), berlin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c.Events) != 1 {
+				t.Fatalf("%d events, want 1", len(c.Events))
+			}
+			if got := c.Events[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("event = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCalendars(t *testing.T) {
+	in := "\ufeffBEGIN:VCALENDAR\nX-WR-CALNAME:Orchestra\\, main\n" +
+		"BEGIN:VTIMEZONE\nTZID:Europe/Berlin\nBEGIN:STANDARD\nDTSTART:19701025T030000\nEND:STANDARD\nEND:VTIMEZONE\n" +
+		"BEGIN:VEVENT\nUID:a\nEND:VEVENT\nEND:VCALENDAR\n\n" +
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:b\nEND:VEVENT\nEND:VCALENDAR"
+	c, err := Parse(strings.NewReader(in), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Calendar{Name: "Orchestra, main", Events: []Event{{UID: "a"}, {UID: "b"}}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Parse = %+v, want %+v", c, want)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"no calendar", "BEGIN:VEVENT\r\nEND:VEVENT\r\n"},
+		{"property outside", "VERSION:2.0\r\nBEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"},
+		{"not ended", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n"},
+		{"unexpected end", "BEGIN:VCALENDAR\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"},
+		{"no colon", calendar("SUMMARY")},
+		{"no name", calendar(":value")},
+		{"malformed parameter", calendar("DTSTART;TZID:20300101T000000")},
+		{"unterminated quote", calendar(`DTSTART;TZID="Europe/Berlin:20300101T000000`)},
+		{"parameter without value", calendar("DTSTART;VALUE=DATE")},
+		{"too long", calendar("SUMMARY:" + strings.Repeat("a", maxLine))},
+		{"too long when unfolded", calendar("SUMMARY:"+strings.Repeat("a", maxLine/2), " "+strings.Repeat("a", maxLine/2))},
+	}
+	for _, tt := range tests {
+		if c, err := Parse(strings.NewReader(tt.in), time.UTC); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Parse = %+v, %v, want ErrInvalid", tt.name, c, err)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{time.Second, "PT1S"},
+		{15 * time.Minute, "PT15M"},
+		{90 * time.Minute, "PT1H30M"},
+		{24 * time.Hour, "P1D"},
+		{26*time.Hour + 5*time.Second, "P1DT2H5S"},
+		{1499 * time.Millisecond, "PT1S"},
+	}
+	for _, tt := range tests {
+		if got := duration(tt.d); got != tt.want {
+			t.Errorf("duration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT1H30M", 90 * time.Minute, false},
+		{"P1W", 7 * 24 * time.Hour, false},
+		{"P1DT2H", 26 * time.Hour, false},
+		{"-PT15M", 15 * time.Minute, false},
+		{"+PT10S", 10 * time.Second, false},
+		{"P", 0, true},
+		{"PT", 0, true},
+		{"1H", 0, true},
+		{"PT1", 0, true},
+		{"P1H", 0, true},
+		{"PT1D", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
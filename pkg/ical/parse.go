@@ -0,0 +1,346 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+)
+
+// ErrInvalid is returned by Parse for data that is not valid iCalendar.
+var ErrInvalid = apperr.New(apperr.Validation, "invalid iCalendar data")
+
+// maxLine bounds the length of unfolded content lines.
+const maxLine = 1 << 20
+
+// property is a content line.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of the calendars in r. Floating times and dates
+// are taken to be in loc, times with a TZID in the IANA time zone it
+// names. Components other than VEVENT, such as VTIMEZONE and VALARM, are
+// skipped, and so are the properties Event has no field for. Events with
+// property values that cannot be read are returned with Invalid set.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	p := &parser{loc: loc, cal: &Calendar{}}
+	if err := p.run(r); err != nil {
+		return nil, err
+	}
+	return p.cal, nil
+}
+
+type parser struct {
+	loc *time.Location
+	cal *Calendar
+	// stack holds the names of the components the current line is in.
+	stack []string
+	// calendars counts the VCALENDARs begun.
+	calendars int
+	event     *Event
+	// duration of the current event, and whether its start is a date.
+	duration *time.Duration
+	allDay   bool
+}
+
+func (p *parser) run(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 4096), maxLine)
+
+	// Content lines are unfolded by joining lines starting with a space
+	// or tab to the line before.
+	var line string
+	start, n := 0, 0
+	for sc.Scan() {
+		n++
+		next := strings.TrimSuffix(sc.Text(), "\r")
+		if n == 1 {
+			next = strings.TrimPrefix(next, "\ufeff")
+		}
+		if len(next) > 0 && (next[0] == ' ' || next[0] == '\t') {
+			if len(line)+len(next) > maxLine {
+				return fmt.Errorf("%w: line %d is too long", ErrInvalid, start)
+			}
+			line += next[1:]
+			continue
+		}
+		if err := p.line(line, start); err != nil {
+			return err
+		}
+		line, start = next, n
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("%w: line %d is too long", ErrInvalid, n+1)
+		}
+		return err
+	}
+	if err := p.line(line, start); err != nil {
+		return err
+	}
+	if len(p.stack) > 0 {
+		return fmt.Errorf("%w: %s is not ended", ErrInvalid, p.stack[len(p.stack)-1])
+	}
+	if p.calendars == 0 {
+		return fmt.Errorf("%w: no VCALENDAR", ErrInvalid)
+	}
+	return nil
+}
+
+// line handles the content line starting at line n of the input.
+func (p *parser) line(s string, n int) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	prop, err := parseLine(s)
+	if err == nil {
+		err = p.property(prop)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: line %d: %v", ErrInvalid, n, err)
+	}
+	return nil
+}
+
+func (p *parser) property(prop property) error {
+	switch prop.name {
+	case "BEGIN":
+		name := strings.ToUpper(prop.value)
+		switch {
+		case len(p.stack) == 0 && name != "VCALENDAR":
+			return fmt.Errorf("expected BEGIN:VCALENDAR")
+		case len(p.stack) == 0:
+			p.calendars++
+		case len(p.stack) == 1 && name == "VEVENT":
+			p.event = &Event{}
+			p.duration, p.allDay = nil, false
+		}
+		p.stack = append(p.stack, name)
+		return nil
+	case "END":
+		name := strings.ToUpper(prop.value)
+		if len(p.stack) == 0 || p.stack[len(p.stack)-1] != name {
+			return fmt.Errorf("unexpected END:%s", name)
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+		if len(p.stack) == 1 && name == "VEVENT" {
+			p.endEvent()
+		}
+		return nil
+	}
+
+	switch {
+	case len(p.stack) == 0:
+		return fmt.Errorf("expected BEGIN:VCALENDAR")
+	case len(p.stack) == 1 && prop.name == "X-WR-CALNAME":
+		p.cal.Name = unescape(prop.value)
+		return nil
+	case len(p.stack) != 2 || p.event == nil:
+		return nil
+	}
+
+	e := p.event
+	var err error
+	switch prop.name {
+	case "UID":
+		e.UID = unescape(prop.value)
+	case "DTSTAMP":
+		e.Stamp, err = p.time(prop, prop.value)
+	case "DTSTART":
+		e.Start, err = p.time(prop, prop.value)
+		p.allDay = isDate(prop, prop.value)
+	case "DTEND":
+		var end time.Time
+		if end, err = p.time(prop, prop.value); err == nil {
+			e.End = &end
+		}
+	case "DURATION":
+		var d time.Duration
+		if d, err = parseDuration(prop.value); err == nil {
+			p.duration = &d
+		}
+	case "SUMMARY":
+		e.Summary = unescape(prop.value)
+	case "DESCRIPTION":
+		e.Description = unescape(prop.value)
+	case "LOCATION":
+		e.Location = unescape(prop.value)
+	case "RRULE":
+		if e.Recurrence != "" {
+			err = fmt.Errorf("events with more than one RRULE are not supported")
+			break
+		}
+		e.Recurrence = prop.value
+	case "EXDATE":
+		for _, v := range strings.Split(prop.value, ",") {
+			var t time.Time
+			if t, err = p.time(prop, v); err != nil {
+				break
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		var t time.Time
+		if t, err = p.time(prop, prop.value); err == nil {
+			e.RecurrenceID = &t
+		}
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(prop.value, "CANCELLED")
+	}
+	if err != nil && e.Invalid == "" {
+		e.Invalid = fmt.Sprintf("%s: %v", prop.name, err)
+	}
+	return nil
+}
+
+// endEvent completes the current event and adds it to the calendar.
+func (p *parser) endEvent() {
+	e := p.event
+	if e.End == nil && !e.Start.IsZero() {
+		switch {
+		case p.duration != nil:
+			end := e.Start.Add(*p.duration)
+			e.End = &end
+		case p.allDay:
+			// An event on a date without an end lasts that day.
+			end := e.Start.AddDate(0, 0, 1)
+			e.End = &end
+		}
+	}
+	sort.Slice(e.ExDates, func(i, j int) bool { return e.ExDates[i].Before(e.ExDates[j]) })
+	p.cal.Events = append(p.cal.Events, *e)
+	p.event = nil
+}
+
+// time parses a DATE or DATE-TIME value of prop.
+func (p *parser) time(prop property, v string) (time.Time, error) {
+	if isDate(prop, v) {
+		return time.ParseInLocation("20060102", v, p.loc)
+	}
+	if strings.HasSuffix(v, "Z") {
+		return time.Parse(TimeFormat, v)
+	}
+	loc := p.loc
+	if tzid := prop.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+	return time.ParseInLocation("20060102T150405", v, loc)
+}
+
+func isDate(prop property, v string) bool {
+	return strings.EqualFold(prop.params["VALUE"], "DATE") || len(v) == len("20060102")
+}
+
+// parseLine splits a content line into its name, parameters and value.
+func parseLine(s string) (property, error) {
+	prop := property{params: map[string]string{}}
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("malformed content line")
+	}
+	prop.name, s = strings.ToUpper(s[:i]), s[i:]
+	for s[0] == ';' {
+		s = s[1:]
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return prop, fmt.Errorf("malformed parameter of %s", prop.name)
+		}
+		name, rest := strings.ToUpper(s[:eq]), s[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return prop, fmt.Errorf("unterminated quote in parameter %s of %s", name, prop.name)
+			}
+			value, s = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return prop, fmt.Errorf("%s has no value", prop.name)
+			}
+			value, s = rest[:end], rest[end:]
+		}
+		prop.params[name] = value
+		if s == "" {
+			return prop, fmt.Errorf("%s has no value", prop.name)
+		}
+	}
+	if s[0] != ':' {
+		return prop, fmt.Errorf("malformed content line")
+	}
+	prop.value = s[1:]
+	return prop, nil
+}
+
+// unescape decodes a TEXT value.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if s[i] == 'n' || s[i] == 'N' {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// parseDuration parses a DURATION value such as PT1H30M or P1W. The sign
+// is ignored, as event durations are positive.
+func parseDuration(s string) (time.Duration, error) {
+	v := strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(v, "P") || len(v) < 3 {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+	}
+	var d time.Duration
+	num := ""
+	for i := 1; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T' && num == "":
+			units = map[byte]time.Duration{
+				'H': time.Hour,
+				'M': time.Minute,
+				'S': time.Second,
+			}
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("malformed duration %q", s)
+			}
+			d += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	return d, nil
+}
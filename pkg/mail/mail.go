@@ -0,0 +1,72 @@
+// Package mail sends the plain text mails of oaf-server.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+// Mailer sends a mail to a single recipient.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New returns a Mailer sending through the configured SMTP server, or one
+// logging the recipient and subject of mails if there is none. Bodies are
+// left out of the log as they hold tokens such as those of invites.
+func New(cfg config.Mail) Mailer {
+	if cfg.SMTPAddress == "" {
+		return logMailer{}
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+type logMailer struct{}
+
+func (logMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("mail to %s: %s (not sent, no SMTP server configured)", to, subject)
+	return nil
+}
+
+type smtpMailer struct {
+	cfg config.Mail
+}
+
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("subject must be a single line")
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(m.cfg.SMTPAddress)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.cfg.From,
+		"To: " + addr.String(),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		strings.ReplaceAll(body, "\n", "\r\n"),
+	}, "\r\n")
+	return smtp.SendMail(m.cfg.SMTPAddress, auth, m.cfg.From, []string{addr.Address}, []byte(msg))
+}
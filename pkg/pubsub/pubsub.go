@@ -0,0 +1,66 @@
+// Package pubsub implements the in-process message bus the GraphQL
+// subscriptions are fed from. Mutations publish to topics and every open
+// subscription to a topic receives the message.
+package pubsub
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// buffer is the number of messages a subscriber may fall behind before
+// further messages to it are dropped.
+const buffer = 16
+
+// Bus delivers messages to the subscribers of a topic. The zero value is
+// not usable, create buses with New.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string]map[chan interface{}]struct{}
+}
+
+// New creates an empty Bus.
+func New() *Bus {
+	return &Bus{subs: map[string]map[chan interface{}]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the messages published to topic
+// until ctx is done, when the channel is closed.
+func (b *Bus) Subscribe(ctx context.Context, topic string) <-chan interface{} {
+	ch := make(chan interface{}, buffer)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = map[chan interface{}]struct{}{}
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs[topic], ch)
+		if len(b.subs[topic]) == 0 {
+			delete(b.subs, topic)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+// Publish sends msg to the current subscribers of topic. It never blocks:
+// subscribers that are too far behind miss the message.
+func (b *Bus) Publish(topic string, msg interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- msg:
+		default:
+			log.Printf("pubsub: dropping message to slow subscriber of %s", topic)
+		}
+	}
+}
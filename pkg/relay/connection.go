@@ -0,0 +1,41 @@
+package relay
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by
+// Cursor.
+var ErrInvalidCursor = apperr.New(apperr.Validation, "invalid cursor")
+
+// Page holds the pagination arguments of a connection field.
+type Page struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// Cursor returns the cursor of the item with the given sort key, the
+// values its list is ordered by. As cursors hold positions rather than
+// items, they stay meaningful when their items are removed from the list.
+func Cursor(key []string) string {
+	b, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor returns the sort key held by cursor.
+func ParseCursor(cursor string) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var key []string
+	if err := json.Unmarshal(b, &key); err != nil || len(key) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return key, nil
+}
@@ -0,0 +1,62 @@
+// Package relay implements the global object identifiers and the cursor
+// connections of the Relay server specification.
+//
+// A global ID encodes the GraphQL type of an object together with its
+// primary key, so that any object can be refetched from its ID alone.
+// Clients must treat global IDs and cursors as opaque.
+package relay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+)
+
+// ErrInvalidID is returned for IDs that are not valid global IDs.
+var ErrInvalidID = apperr.New(apperr.Validation, "invalid ID")
+
+// ToGlobalID returns the global ID of the object of type typ with the
+// primary key id.
+func ToGlobalID(typ, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(typ + ":" + id))
+}
+
+// FromGlobalID splits a global ID into type and primary key.
+func FromGlobalID(gid string) (typ, id string, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(gid)
+	if err != nil {
+		return "", "", ErrInvalidID
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidID
+	}
+	return parts[0], parts[1], nil
+}
+
+// Decode returns the primary key of the global ID gid, which must refer
+// to an object of type typ.
+func Decode(gid, typ string) (string, error) {
+	t, id, err := FromGlobalID(gid)
+	if err != nil {
+		return "", err
+	}
+	if t != typ {
+		return "", fmt.Errorf("%w: expected a %s ID, got a %s ID", ErrInvalidID, typ, t)
+	}
+	return id, nil
+}
+
+// DecodeOptional is Decode for nullable arguments.
+func DecodeOptional(gid *string, typ string) (*string, error) {
+	if gid == nil {
+		return nil, nil
+	}
+	id, err := Decode(*gid, typ)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,87 @@
+package relay
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGlobalID(t *testing.T) {
+	tests := []struct{ typ, id string }{
+		{"User", "9b1b06c0-0ea3-48cf-99cf-d96ee9845e35"},
+		{"Event", "a:b:c"},
+		{"Section", "ü"},
+	}
+	for _, tt := range tests {
+		gid := ToGlobalID(tt.typ, tt.id)
+		typ, id, err := FromGlobalID(gid)
+		if err != nil || typ != tt.typ || id != tt.id {
+			t.Errorf("FromGlobalID(ToGlobalID(%q, %q)) = %q, %q, %v", tt.typ, tt.id, typ, id, err)
+		}
+		if id, err := Decode(gid, tt.typ); err != nil || id != tt.id {
+			t.Errorf("Decode(%q, %q) = %q, %v, want %q", gid, tt.typ, id, err, tt.id)
+		}
+		if _, err := Decode(gid, "Other"); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Decode(%q, %q) = %v, want ErrInvalidID", gid, "Other", err)
+		}
+	}
+}
+
+func TestFromGlobalIDInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"!!!",
+		ToGlobalID("User", ""),
+		ToGlobalID("", "id"),
+		"VXNlcg", // "User", without a colon
+	}
+	for _, gid := range tests {
+		if typ, id, err := FromGlobalID(gid); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("FromGlobalID(%q) = %q, %q, %v, want ErrInvalidID", gid, typ, id, err)
+		}
+	}
+}
+
+func TestDecodeOptional(t *testing.T) {
+	if id, err := DecodeOptional(nil, "User"); id != nil || err != nil {
+		t.Errorf("DecodeOptional(nil) = %v, %v, want nil", id, err)
+	}
+	gid := ToGlobalID("User", "1")
+	if id, err := DecodeOptional(&gid, "User"); err != nil || id == nil || *id != "1" {
+		t.Errorf("DecodeOptional(%q) = %v, %v, want 1", gid, id, err)
+	}
+	if _, err := DecodeOptional(&gid, "Event"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("DecodeOptional(%q, Event) = %v, want ErrInvalidID", gid, err)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	tests := [][]string{
+		{"a"},
+		{"2030-01-07T18:00:00.000000000Z", "id"},
+		{"", "\"quoted\"", "ü"},
+	}
+	for _, key := range tests {
+		got, err := ParseCursor(Cursor(key))
+		if err != nil || !reflect.DeepEqual(got, key) {
+			t.Errorf("ParseCursor(Cursor(%q)) = %q, %v", key, got, err)
+		}
+	}
+}
+
+func TestParseCursorInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"!!!",
+		Cursor(nil),
+		Cursor([]string{}),
+		"bnVsbA",     // null
+		"eyJhIjoxfQ", // {"a":1}
+		"WzEsMl0",    // [1,2]
+	}
+	for _, cursor := range tests {
+		if key, err := ParseCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseCursor(%q) = %q, %v, want ErrInvalidCursor", cursor, key, err)
+		}
+	}
+}
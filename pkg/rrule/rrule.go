@@ -0,0 +1,544 @@
+// Package rrule implements the subset of RFC 5545 recurrence rules that
+// events repeat by: FREQ of DAILY, WEEKLY, MONTHLY or YEARLY together with
+// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY and WKST. Rules using
+// other parts are rejected by Parse.
+//
+// Occurrences keep the wall clock time of the first one in the location it
+// is given in, so that a rehearsal at 19:00 stays at 19:00 across daylight
+// saving time changes.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+)
+
+// ErrInvalid is returned by Parse for malformed or unsupported rules.
+var ErrInvalid = apperr.New(apperr.Validation, "invalid recurrence rule")
+
+const (
+	// UntilFormat is the layout of UNTIL, a date and time in UTC.
+	UntilFormat = "20060102T150405Z"
+	// MaxCount bounds COUNT, as the occurrences of counted rules are
+	// computed from the first one on.
+	MaxCount = 10000
+)
+
+// Frequency is the unit of time a rule repeats in.
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencies = []string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+func (f Frequency) String() string { return frequencies[f] }
+
+var weekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Weekday is a BYDAY entry. N selects the Nth such weekday of the month,
+// or of the year with FREQ=YEARLY, counting from the end when negative;
+// zero selects every such weekday.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+func (w Weekday) String() string {
+	if w.N == 0 {
+		return weekdays[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdays[w.Day]
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// Count limits the number of occurrences, counting the first one.
+	// Zero means no limit.
+	Count int
+	// Until is the last time an occurrence may start at. The zero value
+	// means no limit.
+	Until      time.Time
+	ByMonth    []time.Month
+	ByMonthDay []int
+	ByDay      []Weekday
+	WeekStart  time.Weekday
+}
+
+// Parse parses the value of an RRULE property, such as
+// "FREQ=WEEKLY;BYDAY=TU". A leading "RRULE:" is accepted too.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalid, part)
+		}
+		name, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s is given twice", ErrInvalid, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			hasFreq = true
+			r.Freq, err = parseFrequency(value)
+		case "INTERVAL":
+			r.Interval, err = parsePositive(value)
+		case "COUNT":
+			r.Count, err = parsePositive(value)
+			if err == nil && r.Count > MaxCount {
+				err = fmt.Errorf("%d exceeds the limit of %d", r.Count, MaxCount)
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYMONTH":
+			err = parseList(value, func(v string) error {
+				m, err := parseRange(v, 1, 12, false)
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+				return err
+			})
+		case "BYMONTHDAY":
+			err = parseList(value, func(v string) error {
+				d, err := parseRange(v, 1, 31, true)
+				r.ByMonthDay = append(r.ByMonthDay, d)
+				return err
+			})
+		case "BYDAY":
+			err = parseList(value, func(v string) error {
+				w, err := parseWeekday(v)
+				r.ByDay = append(r.ByDay, w)
+				return err
+			})
+		case "WKST":
+			var w Weekday
+			w, err = parseWeekday(value)
+			if err == nil && w.N != 0 {
+				err = fmt.Errorf("WKST must be a plain weekday")
+			}
+			r.WeekStart = w.Day
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalid, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+		}
+	}
+	if !hasFreq {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalid)
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return r, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("COUNT and UNTIL cannot both be given")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, w := range r.ByDay {
+		if w.N == 0 {
+			continue
+		}
+		switch {
+		case r.Freq == Daily || r.Freq == Weekly:
+			return fmt.Errorf("BYDAY cannot select the Nth weekday with FREQ=%s", r.Freq)
+		case r.Freq == Monthly && (w.N < -5 || w.N > 5):
+			return fmt.Errorf("there is no %s in a month", w)
+		}
+	}
+	return nil
+}
+
+func parseFrequency(s string) (Frequency, error) {
+	for i, f := range frequencies {
+		if s == f {
+			return Frequency(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not supported", s)
+}
+
+// parseUntil parses UNTIL. Besides a time in UTC, calendar applications
+// write dates, which are taken to last until the end of the day in UTC,
+// and floating times, which are taken to be in UTC.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(UntilFormat, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a UTC date and time like 20301231T235959Z")
+}
+
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s is not a positive number", s)
+	}
+	return n, nil
+}
+
+// parseRange parses a number between min and max, or between -max and
+// -min if negative is set.
+func parseRange(s string, min, max int, negative bool) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err == nil && (n >= min && n <= max || negative && n <= -min && n >= -max) {
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s is out of range", s)
+}
+
+func parseList(s string, parse func(string) error) error {
+	for _, v := range strings.Split(s, ",") {
+		if err := parse(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseWeekday(s string) (Weekday, error) {
+	if len(s) < 2 {
+		return Weekday{}, fmt.Errorf("%s is not a weekday", s)
+	}
+	var w Weekday
+	if n := s[:len(s)-2]; n != "" {
+		var err error
+		if w.N, err = parseRange(strings.TrimPrefix(n, "+"), 1, 53, true); err != nil {
+			return Weekday{}, err
+		}
+	}
+	for i, d := range weekdays {
+		if s[len(s)-2:] == d {
+			w.Day = time.Weekday(i)
+			return w, nil
+		}
+	}
+	return Weekday{}, fmt.Errorf("%s is not a weekday", s)
+}
+
+// String formats the rule as the value of an RRULE property. Parts are
+// written in a fixed order, so equal rules give equal strings.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(UntilFormat))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdays[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the starts of the occurrences of a series starting at
+// start that lie within [from, to], in order. Like in iCalendar, start is
+// the first occurrence even if it does not match the rule. Occurrences
+// are computed in the location of start.
+//
+// Without COUNT, the periods before from are skipped, so that the work
+// depends on the length of [from, to] only. Callers bound that length,
+// as there is at most one occurrence a day.
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	if !r.Until.IsZero() && r.Until.Before(to) {
+		to = r.Until
+	}
+	if to.Before(start) {
+		return nil
+	}
+
+	var times []time.Time
+	if !start.Before(from) {
+		times = append(times, start)
+	}
+	count := 1
+	hour, min, sec := start.Clock()
+	last := dateOf(to.In(start.Location()))
+	period := r.firstPeriod(start)
+	if r.Count == 0 {
+		period = r.skipPeriods(period, dateOf(from.In(start.Location())))
+	}
+	for ; !period.After(last); period = r.nextPeriod(period) {
+		for _, day := range r.days(period, start) {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, 0, start.Location())
+			if !t.After(start) {
+				continue
+			}
+			if t.After(to) {
+				return times
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return times
+			}
+			if !t.Before(from) {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// firstPeriod returns the first day of the period start falls in.
+func (r *Rule) firstPeriod(start time.Time) time.Time {
+	y, m, d := start.Date()
+	switch r.Freq {
+	case Weekly:
+		back := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		return date(y, m, d-back)
+	case Monthly:
+		return date(y, m, 1)
+	case Yearly:
+		return date(y, time.January, 1)
+	}
+	return date(y, m, d)
+}
+
+// skipPeriods returns the last period from p on that starts no later than
+// day, or p if day comes first. The periods skipped end before day.
+func (r *Rule) skipPeriods(p, day time.Time) time.Time {
+	// Durations do not span more than 292 years.
+	days := int((day.Unix() - p.Unix()) / (24 * 60 * 60))
+	var n int
+	switch r.Freq {
+	case Daily:
+		n = days / r.Interval
+	case Weekly:
+		n = days / (7 * r.Interval)
+	case Monthly:
+		n = ((day.Year()-p.Year())*12 + int(day.Month()-p.Month())) / r.Interval
+	case Yearly:
+		n = (day.Year() - p.Year()) / r.Interval
+	}
+	if n <= 0 {
+		return p
+	}
+	switch r.Freq {
+	case Monthly:
+		return p.AddDate(0, n*r.Interval, 0)
+	case Yearly:
+		return p.AddDate(n*r.Interval, 0, 0)
+	case Weekly:
+		return p.AddDate(0, 0, 7*n*r.Interval)
+	}
+	return p.AddDate(0, 0, n*r.Interval)
+}
+
+func (r *Rule) nextPeriod(p time.Time) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return p.AddDate(0, 0, 7*r.Interval)
+	case Monthly:
+		return p.AddDate(0, r.Interval, 0)
+	case Yearly:
+		return p.AddDate(r.Interval, 0, 0)
+	}
+	return p.AddDate(0, 0, r.Interval)
+}
+
+// days returns the days of the period starting at p that match the rule,
+// in order. Days are dates at midnight UTC.
+func (r *Rule) days(p time.Time, start time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{p}
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			days = []time.Time{p.AddDate(0, 0, (int(start.Weekday())-int(p.Weekday())+7)%7)}
+			break
+		}
+		for i := 0; i < 7; i++ {
+			days = append(days, p.AddDate(0, 0, i))
+		}
+	case Monthly:
+		days = r.monthDays(p.Year(), p.Month(), start)
+	case Yearly:
+		months := r.ByMonth
+		switch {
+		case len(months) > 0:
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				months = append(months, m)
+			}
+		case len(r.ByDay) > 0:
+			days = r.yearDays(p.Year())
+		default:
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			days = append(days, r.monthDays(p.Year(), m, start)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	matching := days[:0]
+	for i, d := range days {
+		if (i == 0 || !d.Equal(days[i-1])) && r.matches(d) {
+			matching = append(matching, d)
+		}
+	}
+	return matching
+}
+
+// monthDays returns the days of the month selected by BYMONTHDAY and
+// BYDAY, or the day of the month of start if neither is given.
+func (r *Rule) monthDays(y int, m time.Month, start time.Time) []time.Time {
+	last := date(y, m+1, 0).Day()
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + d + 1
+			}
+			if d >= 1 && d <= last {
+				days = append(days, date(y, m, d))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for d := 1; d <= last; d++ {
+			days = append(days, date(y, m, d))
+		}
+	default:
+		if d := start.Day(); d <= last {
+			days = append(days, date(y, m, d))
+		}
+	}
+	return days
+}
+
+// yearDays returns every day of the year, for BYDAY to select from with
+// FREQ=YEARLY and no BYMONTH.
+func (r *Rule) yearDays(y int) []time.Time {
+	var days []time.Time
+	for d := date(y, time.January, 1); d.Year() == y; d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+// matches applies the filters the days of a period are not expanded by
+// to a day.
+func (r *Rule) matches(d time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, d.Month()) {
+		return false
+	}
+	if r.Freq == Daily && len(r.ByMonthDay) > 0 && !r.isMonthDay(d) {
+		return false
+	}
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	yearly := r.Freq == Yearly && len(r.ByMonth) == 0
+	for _, w := range r.ByDay {
+		if d.Weekday() != w.Day {
+			continue
+		}
+		if w.N == 0 || nth(d, yearly) == w.N || nthFromEnd(d, yearly) == w.N {
+			return true
+		}
+	}
+	return false
+}
+
+// nth returns which occurrence of its weekday d is within its month, or
+// within its year if yearly is set, counting from 1.
+func nth(d time.Time, yearly bool) int {
+	if yearly {
+		return (d.YearDay()-1)/7 + 1
+	}
+	return (d.Day()-1)/7 + 1
+}
+
+// nthFromEnd is nth counting from the end, from -1.
+func nthFromEnd(d time.Time, yearly bool) int {
+	if yearly {
+		days := date(d.Year(), time.December, 31).YearDay()
+		return -((days-d.YearDay())/7 + 1)
+	}
+	last := date(d.Year(), d.Month()+1, 0).Day()
+	return -((last-d.Day())/7 + 1)
+}
+
+func (r *Rule) isMonthDay(d time.Time) bool {
+	last := date(d.Year(), d.Month()+1, 0).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || md < 0 && last+md+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, o := range months {
+		if o == m {
+			return true
+		}
+	}
+	return false
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dateOf returns the date of t in its location as a day of the periods.
+func dateOf(t time.Time) time.Time {
+	return date(t.Date())
+}
@@ -0,0 +1,280 @@
+package rrule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=TU", "FREQ=WEEKLY;BYDAY=TU"},
+		{"rrule:freq=weekly;byday=tu,th", "FREQ=WEEKLY;BYDAY=TU,TH"},
+		{"FREQ=WEEKLY;INTERVAL=1", "FREQ=WEEKLY"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO;WKST=SU"},
+		{"FREQ=WEEKLY;WKST=MO", "FREQ=WEEKLY"},
+		{"FREQ=DAILY;COUNT=10", "FREQ=DAILY;COUNT=10"},
+		{"FREQ=DAILY;UNTIL=20301231T235959Z", "FREQ=DAILY;UNTIL=20301231T235959Z"},
+		{"FREQ=DAILY;UNTIL=20301231T120000", "FREQ=DAILY;UNTIL=20301231T120000Z"},
+		{"FREQ=DAILY;UNTIL=20301231", "FREQ=DAILY;UNTIL=20301231T235959Z"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"FREQ=MONTHLY;BYDAY=+2MO,-1FR", "FREQ=MONTHLY;BYDAY=2MO,-1FR"},
+		{"FREQ=YEARLY;BYMONTH=3,10;BYDAY=-1SU", "FREQ=YEARLY;BYMONTH=3,10;BYDAY=-1SU"},
+		{"FREQ=YEARLY;BYDAY=20MO", "FREQ=YEARLY;BYDAY=20MO"},
+		{"  FREQ=DAILY  ", "FREQ=DAILY"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := Parse(r.String())
+		if err != nil || !reflect.DeepEqual(again, r) {
+			t.Errorf("Parse(%q) does not round trip: %+v, %v, want %+v", r.String(), again, err, r)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=10001",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;COUNT=3;UNTIL=20301231T235959Z",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;BYMONTH=13",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=YEARLY;BYDAY=54MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;WKST=1MO",
+		"FREQ=WEEKLY;BYDAY=MO,",
+		"FREQ=DAILY;",
+	}
+	for _, in := range tests {
+		if r, err := Parse(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) = %v, %v, want ErrInvalid", in, r, err)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		name     string
+		rule     string
+		start    string
+		from, to string
+		want     []string
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-01-03 23:59",
+			want: []string{"2030-01-01 19:00", "2030-01-02 19:00", "2030-01-03 19:00"},
+		},
+		{
+			name:  "before from is left out",
+			rule:  "FREQ=DAILY",
+			start: "2030-01-01 19:00", from: "2030-01-10 19:00", to: "2030-01-11 18:59",
+			want: []string{"2030-01-10 19:00"},
+		},
+		{
+			name:  "to is inclusive",
+			rule:  "FREQ=DAILY;INTERVAL=2",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-01-05 19:00",
+			want: []string{"2030-01-01 19:00", "2030-01-03 19:00", "2030-01-05 19:00"},
+		},
+		{
+			name:  "ends before start",
+			rule:  "FREQ=DAILY",
+			start: "2030-01-10 19:00", from: "2030-01-01 00:00", to: "2030-01-09 00:00",
+		},
+		{
+			name:  "count counts the first occurrence",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-12-31 00:00",
+			want: []string{"2030-01-01 19:00", "2030-01-08 19:00", "2030-01-15 19:00"},
+		},
+		{
+			name:  "count counts occurrences before from",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: "2030-01-01 19:00", from: "2030-01-10 00:00", to: "2030-12-31 00:00",
+			want: []string{"2030-01-15 19:00"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20300103T180000Z",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-12-31 00:00",
+			want: []string{"2030-01-01 19:00", "2030-01-02 19:00", "2030-01-03 19:00"},
+		},
+		{
+			name:  "until as a date",
+			rule:  "FREQ=DAILY;UNTIL=20300102",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-12-31 00:00",
+			want: []string{"2030-01-01 19:00", "2030-01-02 19:00"},
+		},
+		{
+			name:  "start need not match",
+			rule:  "FREQ=WEEKLY;BYDAY=TU,TH",
+			start: "2030-01-07 19:00", from: "2030-01-01 00:00", to: "2030-01-14 00:00",
+			want: []string{"2030-01-07 19:00", "2030-01-08 19:00", "2030-01-10 19:00"},
+		},
+		{
+			name:  "every other week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			start: "2030-01-07 19:00", from: "2030-01-01 00:00", to: "2030-02-01 00:00",
+			want: []string{"2030-01-07 19:00", "2030-01-11 19:00", "2030-01-21 19:00", "2030-01-25 19:00"},
+		},
+		{
+			name:  "week start decides the weeks skipped",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU",
+			start: "2030-01-01 19:00", from: "2030-01-01 00:00", to: "2030-01-31 00:00",
+			want: []string{"2030-01-01 19:00", "2030-01-13 19:00", "2030-01-15 19:00", "2030-01-27 19:00", "2030-01-29 19:00"},
+		},
+		{
+			name:  "months without the day are skipped",
+			rule:  "FREQ=MONTHLY",
+			start: "2030-01-31 19:00", from: "2030-01-01 00:00", to: "2030-06-01 00:00",
+			want: []string{"2030-01-31 19:00", "2030-03-31 19:00", "2030-05-31 19:00"},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2030-01-31 19:00", from: "2030-01-01 00:00", to: "2030-04-01 00:00",
+			want: []string{"2030-01-31 19:00", "2030-02-28 19:00", "2030-03-31 19:00"},
+		},
+		{
+			name:  "nth weekday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=2MO,-1FR",
+			start: "2030-01-14 19:00", from: "2030-01-01 00:00", to: "2030-03-01 00:00",
+			want: []string{"2030-01-14 19:00", "2030-01-25 19:00", "2030-02-11 19:00", "2030-02-22 19:00"},
+		},
+		{
+			name:  "yearly by month and day",
+			rule:  "FREQ=YEARLY;BYMONTH=3,10;BYDAY=-1SU",
+			start: "2030-03-31 10:00", from: "2030-01-01 00:00", to: "2031-12-31 00:00",
+			want: []string{"2030-03-31 10:00", "2030-10-27 10:00", "2031-03-30 10:00", "2031-10-26 10:00"},
+		},
+		{
+			name:  "leap day",
+			rule:  "FREQ=YEARLY",
+			start: "2028-02-29 12:00", from: "2028-01-01 00:00", to: "2036-12-31 00:00",
+			want: []string{"2028-02-29 12:00", "2032-02-29 12:00", "2036-02-29 12:00"},
+		},
+		{
+			name:  "wall clock time kept into summer time",
+			rule:  "FREQ=DAILY",
+			start: "2030-03-30 19:00", from: "2030-03-30 00:00", to: "2030-04-01 00:00",
+			want: []string{"2030-03-30 19:00", "2030-03-31 19:00"},
+		},
+		{
+			name:  "wall clock time kept into winter time",
+			rule:  "FREQ=WEEKLY",
+			start: "2030-10-20 19:00", from: "2030-10-01 00:00", to: "2030-11-01 00:00",
+			want: []string{"2030-10-20 19:00", "2030-10-27 19:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tm := range r.Between(at(tt.start), at(tt.from), at(tt.to)) {
+				if tm.Location() != berlin {
+					t.Errorf("%v is not in the location of start", tm)
+				}
+				got = append(got, tm.Format("2006-01-02 15:04"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Between = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBetweenDST checks that occurrences keep their wall clock time, and
+// so move in UTC, when daylight saving time starts or ends.
+func TestBetweenDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	r, err := Parse("FREQ=DAILY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 3, 30, 19, 0, 0, 0, berlin)
+	got := r.Between(start, start, start.AddDate(0, 0, 7))
+	want := []time.Time{
+		time.Date(2030, 3, 30, 18, 0, 0, 0, time.UTC),
+		time.Date(2030, 3, 31, 17, 0, 0, 0, time.UTC),
+		time.Date(2030, 4, 1, 17, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Between = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, got[i].UTC(), want[i])
+		}
+	}
+}
+
+// TestBetweenWindow checks that the occurrences of an unbounded rule do
+// not depend on the window they are looked up in.
+func TestBetweenWindow(t *testing.T) {
+	r, err := Parse("FREQ=WEEKLY;INTERVAL=3;BYDAY=MO,WE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 2, 19, 0, 0, 0, time.UTC)
+	end := start.AddDate(2, 0, 0)
+	all := r.Between(start, start, end)
+	for from := start; from.Before(end); from = from.AddDate(0, 1, 3) {
+		to := from.AddDate(0, 2, 0)
+		if to.After(end) {
+			to = end
+		}
+		var want []time.Time
+		for _, tm := range all {
+			if !tm.Before(from) && !tm.After(to) {
+				want = append(want, tm)
+			}
+		}
+		if got := r.Between(start, from, to); !reflect.DeepEqual(got, want) {
+			t.Errorf("Between(%v, %v) = %v, want %v", from, to, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// presentError puts the code of err into its extensions, along with the
+// fields a validation error is about. Errors without a code are logged
+// and reported as internal errors, as their messages may reveal details
+// such as SQL statements. Errors of the request as a whole keep their
+// codes.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	var gqlErr *gqlerror.Error
+	if !errors.As(err, &gqlErr) {
+		gqlErr = gqlerror.WrapPath(graphql.GetPath(ctx), err)
+	}
+
+	var e *apperr.Error
+	if !errors.As(err, &e) {
+		if _, ok := gqlErr.Extensions["code"]; ok {
+			// Errors of the request as a whole, such as parse errors,
+			// come with a code of their own.
+			return gqlErr
+		}
+		log.Printf("internal error at %s: %v", gqlErr.Path, err)
+		gqlErr = &gqlerror.Error{
+			Message:   "internal error",
+			Path:      gqlErr.Path,
+			Locations: gqlErr.Locations,
+		}
+		errcode.Set(gqlErr, string(apperr.Internal))
+		return gqlErr
+	}
+	errcode.Set(gqlErr, string(e.Code))
+	if len(e.Fields) > 0 {
+		gqlErr.Extensions["fields"] = e.Fields
+	}
+	return gqlErr
+}
+
+// recoverPanic logs a panic in a resolver with its stack trace, which is
+// kept from the client.
+func recoverPanic(ctx context.Context, p interface{}) error {
+	log.Printf("panic at %s: %v\n%s", graphql.GetPath(ctx), p, debug.Stack())
+	return apperr.New(apperr.Internal, "internal error")
+}
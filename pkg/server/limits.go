@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/concertLabs/oaf-server/pkg/config"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Error codes of operations exceeding the limits.
+const (
+	errComplexityLimit = "COMPLEXITY_LIMIT_EXCEEDED"
+	errDepthLimit      = "DEPTH_LIMIT_EXCEEDED"
+)
+
+// limits rejects operations exceeding the configured complexity or depth
+// before they are executed. Errors carry the code, the value found and
+// the limit in their extensions.
+type limits struct {
+	cfg config.Limits
+	es  graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &limits{}
+
+func (l *limits) ExtensionName() string {
+	return "Limits"
+}
+
+// Validate makes sure the fields costs are configured for exist.
+func (l *limits) Validate(es graphql.ExecutableSchema) error {
+	for field := range l.cfg.Costs {
+		parts := strings.SplitN(field, ".", 2)
+		def := es.Schema().Types[parts[0]]
+		if def == nil || def.Fields.ForName(parts[1]) == nil {
+			return fmt.Errorf("server.limits.costs: unknown field %s", field)
+		}
+	}
+	l.es = es
+	return nil
+}
+
+func (l *limits) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if max := l.cfg.MaxDepth; max > 0 {
+		if d := depth(rc.Operation.SelectionSet); d > max {
+			err := gqlerror.Errorf("operation has a depth of %d, which exceeds the limit of %d", d, max)
+			errcode.Set(err, errDepthLimit)
+			err.Extensions["depth"] = d
+			err.Extensions["limit"] = max
+			return err
+		}
+	}
+	if max := l.cfg.MaxComplexity; max > 0 {
+		c := complexity.Calculate(costs{l.es, l.cfg.Costs}, rc.Operation, rc.Variables)
+		if c > max {
+			err := gqlerror.Errorf("operation has a complexity of %d, which exceeds the limit of %d", c, max)
+			errcode.Set(err, errComplexityLimit)
+			err.Extensions["complexity"] = c
+			err.Extensions["limit"] = max
+			return err
+		}
+	}
+	return nil
+}
+
+// depth returns how deeply the fields of set nest. Introspection fields
+// count like any other, as the introspection types are cyclic too:
+// __type { fields { type { fields { ... } } } } nests without end. Only
+// __typename is left out, which selects nothing.
+func depth(set ast.SelectionSet) int {
+	max := 0
+	for _, sel := range set {
+		d := 0
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if sel.Name == "__typename" {
+				continue
+			}
+			d = 1 + depth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			d = depth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				d = depth(sel.Definition.SelectionSet)
+			}
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// costs adds the configured costs of fields to the complexity estimated
+// by the schema, in which every field costs 1.
+type costs struct {
+	graphql.ExecutableSchema
+	byField map[string]int
+}
+
+func (c costs) Complexity(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+	n, ok := c.ExecutableSchema.Complexity(typeName, fieldName, childComplexity, args)
+	if !ok {
+		n = 1 + childComplexity
+	}
+	if cost, ok := c.byField[typeName+"."+fieldName]; ok {
+		n += cost - 1
+	}
+	return n, true
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/concertLabs/oaf-server/pkg/config"
+	"github.com/concertLabs/oaf-server/pkg/graph/generated"
+	"github.com/vektah/gqlparser/v2"
+)
+
+// typeRef is the TypeRef fragment of the introspection query of GraphiQL.
+const typeRef = `
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+    ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+func TestDepth(t *testing.T) {
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{`{ me { user { id } } }`, 3},
+		{`{ __typename me { __typename user { __typename id } } }`, 3},
+		{`{ me { ...F } } fragment F on Viewer { organizations { id } }`, 3},
+		{`{ me { ... on Viewer { organizations { id } } } }`, 3},
+		{`{ __schema { queryType { name } } }`, 3},
+		{`{ __schema { types { fields { type { ...TypeRef } } } } }` + typeRef, 12},
+		{`{ __type(name: "User") { fields { type { fields { type { fields { type { name } } } } } } } }`, 8},
+	}
+	for _, tt := range tests {
+		doc, err := gqlparser.LoadQuery(schema, tt.query)
+		if err != nil {
+			t.Errorf("LoadQuery(%q) = %v", tt.query, err)
+			continue
+		}
+		if got := depth(doc.Operations[0].SelectionSet); got != tt.want {
+			t.Errorf("depth(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestDepthLimitIntrospection checks that nesting introspection fields
+// runs into the depth limit, while the introspection query of GraphiQL
+// stays within the default one.
+func TestDepthLimitIntrospection(t *testing.T) {
+	es := generated.NewExecutableSchema(generated.Config{})
+	l := &limits{cfg: config.Default().Server.Limits}
+	if err := l.Validate(es); err != nil {
+		t.Fatal(err)
+	}
+	nested := `{ __type(name: "User") {` + strings.Repeat(` fields { type {`, 10) + ` name` + strings.Repeat(` } }`, 10) + ` } }`
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{`{ __schema { types { name fields { name type { ...TypeRef } } } } }` + typeRef, ""},
+		{nested, errDepthLimit},
+	}
+	for _, tt := range tests {
+		doc, err := gqlparser.LoadQuery(es.Schema(), tt.query)
+		if err != nil {
+			t.Fatalf("LoadQuery(%q) = %v", tt.query, err)
+		}
+		rc := &graphql.OperationContext{Operation: doc.Operations[0]}
+		gqlErr := l.MutateOperationContext(context.Background(), rc)
+		var code interface{} = ""
+		if gqlErr != nil {
+			code = gqlErr.Extensions["code"]
+		}
+		if code != tt.code {
+			t.Errorf("MutateOperationContext(%q) = %v, want code %q", tt.query, gqlErr, tt.code)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errNotAllowed is the error code of operations rejected in strict mode.
+const errNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+
+// allowlist resolves the hashes of persisted queries listed in a manifest
+// to their queries. In strict mode, it rejects every other operation,
+// including ones sent in full.
+type allowlist struct {
+	path   string
+	strict bool
+	// queries maps the SHA-256 hashes of the operations to their queries.
+	queries map[string]string
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = &allowlist{}
+
+func (a *allowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+// Validate loads the manifest and makes sure its operations are valid
+// against es, so that clients do not find out in production.
+func (a *allowlist) Validate(es graphql.ExecutableSchema) error {
+	queries, err := loadManifest(a.path)
+	if err != nil {
+		return fmt.Errorf("server.persistedQueries.manifest: %w", err)
+	}
+	for hash, query := range queries {
+		if _, errs := gqlparser.LoadQuery(es.Schema(), query); errs != nil {
+			return fmt.Errorf("server.persistedQueries.manifest: operation %s: %w", hash, errs)
+		}
+	}
+	a.queries = queries
+	return nil
+}
+
+func (a *allowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	if rawParams.Query == "" {
+		if query, ok := a.queries[persistedQueryHash(rawParams)]; ok {
+			rawParams.Query = query
+			return nil
+		}
+	} else if _, ok := a.queries[sha256Hex(rawParams.Query)]; ok {
+		return nil
+	}
+	if !a.strict {
+		return nil
+	}
+	err := gqlerror.Errorf("only the operations of the persisted query manifest may be executed")
+	errcode.Set(err, errNotAllowed)
+	return err
+}
+
+// persistedQueryHash returns the hash the client sent the operation by,
+// if any.
+func persistedQueryHash(rawParams *graphql.RawParams) string {
+	ext, _ := rawParams.Extensions["persistedQuery"].(map[string]interface{})
+	hash, _ := ext["sha256Hash"].(string)
+	return hash
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// apolloManifest is the format of the manifests generated by the Apollo
+// tooling.
+type apolloManifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// loadManifest reads the queries of the manifest at path, either an
+// object mapping hashes to queries or an Apollo manifest, and makes sure
+// the hashes match.
+func loadManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	queries := map[string]string{}
+	if _, ok := fields["format"]; ok {
+		var m apolloManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		if m.Format != "apollo-persisted-query-manifest" || m.Version != 1 {
+			return nil, fmt.Errorf("unsupported manifest format %q version %d", m.Format, m.Version)
+		}
+		for _, op := range m.Operations {
+			queries[op.ID] = op.Body
+		}
+	} else if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, err
+	}
+
+	for hash, query := range queries {
+		if sha256Hex(query) != hash {
+			return nil, fmt.Errorf("operation %s does not match its hash", hash)
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,146 @@
+// Package server exposes the GraphQL API over HTTP.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/concertLabs/oaf-server/pkg/auth"
+	"github.com/concertLabs/oaf-server/pkg/cache"
+	"github.com/concertLabs/oaf-server/pkg/config"
+)
+
+// QueryPath is the endpoint GraphQL operations are posted to.
+const QueryPath = "/query"
+
+// Server serves an executable schema.
+type Server struct {
+	cfg  config.Server
+	http *http.Server
+}
+
+// New creates a Server for es using cfg. Requests to the query endpoint
+// are authenticated by authService and handled with the given extensions
+// in addition to the default ones. calendars serves the iCalendar feeds
+// below calendarPath.
+func New(cfg config.Server, es graphql.ExecutableSchema, authService *auth.Service, calendarPath string, calendars http.Handler, extensions ...graphql.HandlerExtension) (*Server, error) {
+	// Extensions are validated on use, which panics if they are invalid.
+	queryLimits := &limits{cfg: cfg.Limits}
+	if err := queryLimits.Validate(es); err != nil {
+		return nil, err
+	}
+	var persisted *allowlist
+	if cfg.PersistedQueries.Manifest != "" {
+		persisted = &allowlist{path: cfg.PersistedQueries.Manifest, strict: cfg.PersistedQueries.Strict}
+		if err := persisted.Validate(es); err != nil {
+			return nil, err
+		}
+	}
+	srv := newHandler(es, authService, cfg.PersistedQueries, persisted)
+	srv.Use(queryLimits)
+	for _, ext := range extensions {
+		srv.Use(ext)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(QueryPath, authService.Middleware(srv))
+	mux.Handle(calendarPath, calendars)
+	if cfg.Playground {
+		mux.Handle("/", playground.Handler("oaf-server", QueryPath))
+	}
+
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:    cfg.Address,
+			Handler: mux,
+		},
+	}, nil
+}
+
+// newHandler mirrors handler.NewDefaultServer with three differences.
+// WebSocket clients may authenticate in their connection_init payload, as
+// browsers cannot set headers on the upgrade request. Errors are
+// presented with their codes. Persisted queries are configured by cfg:
+// the operations of the manifest persisted, if not nil, are resolved
+// before those clients registered, and in strict mode clients cannot
+// register any.
+func newHandler(es graphql.ExecutableSchema, authService *auth.Service, cfg config.PersistedQueries, persisted *allowlist) *handler.Server {
+	srv := handler.New(es)
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc: func(ctx context.Context, payload transport.InitPayload) (context.Context, error) {
+			header := payload.Authorization()
+			if header == "" {
+				return ctx, nil
+			}
+			u, err := authService.Authenticate(ctx, strings.TrimPrefix(header, "Bearer "))
+			if err != nil {
+				return nil, err
+			}
+			return auth.WithUser(ctx, u), nil
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(1000))
+	srv.SetErrorPresenter(presentError)
+	srv.SetRecoverFunc(recoverPanic)
+
+	srv.Use(extension.Introspection{})
+	if persisted != nil {
+		srv.Use(persisted)
+	}
+	if !cfg.Strict {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: cache.New(cfg),
+		})
+	}
+
+	return srv
+}
+
+// Run listens until ctx is cancelled and then shuts the server down,
+// giving in-flight requests up to the configured timeout to finish.
+func (s *Server) Run(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", s.cfg.Address)
+		if s.cfg.TLS.Enabled() {
+			errc <- s.http.ListenAndServeTLS(s.cfg.TLS.CertFile, s.cfg.TLS.KeyFile)
+		} else {
+			errc <- s.http.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down")
+	sctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(sctx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type attendeeRepo struct {
+	*db
+}
+
+func (r *attendeeRepo) Get(ctx context.Context, id string) (*model.Attendee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.attendees[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *attendeeRepo) GetByEventUser(ctx context.Context, eventID, userID string, occurrence *time.Time) (*model.Attendee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, a := range r.attendees {
+		if a.EventID == eventID && a.UserID == userID && sameTime(a.Occurrence, occurrence) {
+			return &a, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *attendeeRepo) List(ctx context.Context, f storage.AttendeeFilter) ([]*model.Attendee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attendees := r.list(f)
+	lo, hi, err := page(3, len(attendees), func(i int) storage.Key { return storage.AttendeeKey(attendees[i]) }, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	return attendees[lo:hi], nil
+}
+
+func (r *attendeeRepo) Count(ctx context.Context, f storage.AttendeeFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.list(f)), nil
+}
+
+// list returns the attendees matching f but for its page, in order. It
+// expects r.mu to be held.
+func (r *attendeeRepo) list(f storage.AttendeeFilter) []*model.Attendee {
+	var attendees []*model.Attendee
+	for _, a := range r.attendees {
+		if !matches(f.EventID, a.EventID) || !matchesAny(f.EventIDs, a.EventID) || !matches(f.UserID, a.UserID) {
+			continue
+		}
+		if f.Occurrence != nil && !sameTime(f.Occurrence, a.Occurrence) {
+			continue
+		}
+		if f.Commitment != nil && *f.Commitment != a.Commitment {
+			continue
+		}
+		a := a
+		attendees = append(attendees, &a)
+	}
+	sort.Slice(attendees, func(i, j int) bool {
+		return storage.AttendeeKey(attendees[i]).Less(storage.AttendeeKey(attendees[j]))
+	})
+	return attendees
+}
+
+func (r *attendeeRepo) Create(ctx context.Context, a *model.Attendee) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUser(a.UserID); err != nil {
+		return err
+	}
+	if err := r.checkEvent(a.EventID); err != nil {
+		return err
+	}
+	if err := r.checkUnique(a); err != nil {
+		return err
+	}
+	a.ID = newID()
+	r.attendees[a.ID] = *a
+	return nil
+}
+
+func (r *attendeeRepo) checkUnique(a *model.Attendee) error {
+	for _, o := range r.attendees {
+		if o.ID != a.ID && o.EventID == a.EventID && o.UserID == a.UserID && sameTime(o.Occurrence, a.Occurrence) {
+			return conflict("user %s already responded to event %s", a.UserID, a.EventID)
+		}
+	}
+	return nil
+}
+
+func (r *attendeeRepo) Update(ctx context.Context, a *model.Attendee) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.attendees[a.ID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if err := r.checkEvent(a.EventID); err != nil {
+		return err
+	}
+	if err := r.checkUnique(a); err != nil {
+		return err
+	}
+	stored.EventID = a.EventID
+	stored.Occurrence = a.Occurrence
+	stored.Commitment = a.Commitment
+	stored.Comment = a.Comment
+	r.attendees[a.ID] = stored
+	return nil
+}
+
+func (r *attendeeRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.attendees[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.attendees, id)
+	return nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type commentRepo struct {
+	*db
+}
+
+func (r *commentRepo) Get(ctx context.Context, id string) (*model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.comments[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *commentRepo) ListByEvent(ctx context.Context, eventID string, p *storage.Page) ([]*model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comments := r.list(eventID)
+	lo, hi, err := page(2, len(comments), func(i int) storage.Key { return storage.CommentKey(comments[i]) }, p)
+	if err != nil {
+		return nil, err
+	}
+	return comments[lo:hi], nil
+}
+
+func (r *commentRepo) CountByEvent(ctx context.Context, eventID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.list(eventID)), nil
+}
+
+// list returns the comments of the event in order. It expects r.mu to be
+// held.
+func (r *commentRepo) list(eventID string) []*model.Comment {
+	var comments []*model.Comment
+	for _, c := range r.comments {
+		if c.EventID == eventID {
+			c := c
+			comments = append(comments, &c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return storage.CommentKey(comments[i]).Less(storage.CommentKey(comments[j]))
+	})
+	return comments
+}
+
+func (r *commentRepo) Create(ctx context.Context, c *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUser(c.CreatorID); err != nil {
+		return err
+	}
+	if err := r.checkEvent(c.EventID); err != nil {
+		return err
+	}
+	c.ID = newID()
+	c.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	r.comments[c.ID] = *c
+	return nil
+}
+
+func (r *commentRepo) Update(ctx context.Context, c *model.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.comments[c.ID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	stored.Text = c.Text
+	r.comments[c.ID] = stored
+	return nil
+}
+
+func (r *commentRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.comments[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.comments, id)
+	return nil
+}
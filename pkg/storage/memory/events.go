@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type eventRepo struct {
+	*db
+}
+
+func (r *eventRepo) Get(ctx context.Context, id string) (*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.events[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return copyEvent(e), nil
+}
+
+func (r *eventRepo) GetMany(ctx context.Context, ids []string) ([]*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []*model.Event
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		e, ok := r.events[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		events = append(events, copyEvent(e))
+	}
+	return events, nil
+}
+
+func (r *eventRepo) List(ctx context.Context, f storage.EventFilter) ([]*model.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := r.list(f)
+	lo, hi, err := page(2, len(events), func(i int) storage.Key { return storage.EventKey(events[i]) }, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	return events[lo:hi], nil
+}
+
+func (r *eventRepo) Count(ctx context.Context, f storage.EventFilter) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.list(f)), nil
+}
+
+// list returns the events matching f but for its page, in order. It
+// expects r.mu to be held.
+func (r *eventRepo) list(f storage.EventFilter) []*model.Event {
+	var events []*model.Event
+	for _, e := range r.events {
+		if f.OrganizationID != nil && (e.OrganizationID == nil || *e.OrganizationID != *f.OrganizationID) {
+			continue
+		}
+		if !matchesNullable(f.SeriesID, e.SeriesID) || !matchesNullable(f.UID, e.UID) {
+			continue
+		}
+		if f.SeriesIDs != nil && (e.SeriesID == nil || !matchesAny(f.SeriesIDs, *e.SeriesID)) {
+			continue
+		}
+		if f.Recurring != nil && *f.Recurring != (e.Recurrence != nil) {
+			continue
+		}
+		if f.Start != nil && e.Start.Before(*f.Start) && e.Recurrence == nil {
+			continue
+		}
+		if f.End != nil && e.Start.After(*f.End) {
+			continue
+		}
+		events = append(events, copyEvent(e))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return storage.EventKey(events[i]).Less(storage.EventKey(events[j]))
+	})
+	return events
+}
+
+func (r *eventRepo) Create(ctx context.Context, e *model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkRefs(e); err != nil {
+		return err
+	}
+	e.ID = newID()
+	r.events[e.ID] = *copyEvent(*e)
+	return nil
+}
+
+func (r *eventRepo) Update(ctx context.Context, e *model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[e.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if err := r.checkRefs(e); err != nil {
+		return err
+	}
+	r.events[e.ID] = *copyEvent(*e)
+	return nil
+}
+
+func (r *eventRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[id]; !ok {
+		return storage.ErrNotFound
+	}
+	r.deleteEvent(id)
+	return nil
+}
+
+func (r *eventRepo) checkRefs(e *model.Event) error {
+	if err := r.checkUser(e.CreatorID); err != nil {
+		return err
+	}
+	if e.OrganizationID != nil {
+		if _, ok := r.organizations[*e.OrganizationID]; !ok {
+			return conflict("organization %s does not exist", *e.OrganizationID)
+		}
+	}
+	seen := map[string]bool{}
+	for _, id := range e.SectionIDs {
+		if err := r.checkSection(id); err != nil {
+			return err
+		}
+		if seen[id] {
+			return conflict("section %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+	if e.SeriesID != nil {
+		if err := r.checkEvent(*e.SeriesID); err != nil {
+			return err
+		}
+		for _, o := range r.events {
+			if o.ID != e.ID && o.SeriesID != nil && *o.SeriesID == *e.SeriesID && sameTime(o.RecurrenceID, e.RecurrenceID) {
+				return conflict("occurrence of event %s is replaced already", *e.SeriesID)
+			}
+		}
+	}
+	if e.UID != nil && e.OrganizationID != nil {
+		for _, o := range r.events {
+			if o.ID != e.ID && o.UID != nil && *o.UID == *e.UID && o.OrganizationID != nil && *o.OrganizationID == *e.OrganizationID {
+				return conflict("event %s has been imported already", *e.UID)
+			}
+		}
+	}
+	return nil
+}
+
+// copyEvent returns a copy of e that does not share its SectionIDs and
+// ExDates, which it keeps ordered like the SQL store returns them.
+func copyEvent(e model.Event) *model.Event {
+	e.SectionIDs = append([]string(nil), e.SectionIDs...)
+	sort.Strings(e.SectionIDs)
+	if len(e.ExDates) == 0 {
+		e.ExDates = nil
+	} else {
+		e.ExDates = append([]time.Time(nil), e.ExDates...)
+	}
+	return &e
+}
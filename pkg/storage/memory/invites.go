@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type inviteRepo struct {
+	*db
+}
+
+func (r *inviteRepo) Get(ctx context.Context, id string) (*model.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.invites[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &i, nil
+}
+
+func (r *inviteRepo) GetByTokenHash(ctx context.Context, hash string) (*model.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, i := range r.invites {
+		if i.TokenHash != nil && *i.TokenHash == hash {
+			return &i, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *inviteRepo) List(ctx context.Context, f storage.InviteFilter) ([]*model.Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var invites []*model.Invite
+	for _, i := range r.invites {
+		if !matches(f.SectionID, i.SectionID) || !matchesNullable(f.UserID, i.UserID) {
+			continue
+		}
+		i := i
+		invites = append(invites, &i)
+	}
+	sort.Slice(invites, func(a, b int) bool {
+		return storage.InviteKey(invites[a]).Less(storage.InviteKey(invites[b]))
+	})
+	lo, hi, err := page(2, len(invites), func(i int) storage.Key { return storage.InviteKey(invites[i]) }, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	return invites[lo:hi], nil
+}
+
+func (r *inviteRepo) Create(ctx context.Context, i *model.Invite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i.UserID != nil {
+		if err := r.checkUser(*i.UserID); err != nil {
+			return err
+		}
+	}
+	if i.InvitedByID != nil {
+		if err := r.checkUser(*i.InvitedByID); err != nil {
+			return err
+		}
+	}
+	if err := r.checkSection(i.SectionID); err != nil {
+		return err
+	}
+	for _, o := range r.invites {
+		if o.SectionID != i.SectionID {
+			continue
+		}
+		if i.UserID != nil && o.UserID != nil && *o.UserID == *i.UserID {
+			return conflict("user %s is already invited to section %s", *i.UserID, i.SectionID)
+		}
+		if i.Email != nil && o.Email != nil && *o.Email == *i.Email {
+			return conflict("%s is already invited to section %s", *i.Email, i.SectionID)
+		}
+	}
+	i.ID = newID()
+	r.invites[i.ID] = *i
+	return nil
+}
+
+func (r *inviteRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invites[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.invites, id)
+	return nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type memberRepo struct {
+	*db
+}
+
+func (r *memberRepo) Get(ctx context.Context, id string) (*model.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.members[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &m, nil
+}
+
+func (r *memberRepo) GetBySectionUser(ctx context.Context, sectionID, userID string) (*model.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.members {
+		if m.SectionID == sectionID && m.UserID == userID {
+			return &m, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *memberRepo) List(ctx context.Context, f storage.MemberFilter) ([]*model.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var members []*model.Member
+	for _, m := range r.members {
+		if !matches(f.SectionID, m.SectionID) || !matchesAny(f.SectionIDs, m.SectionID) || !matches(f.UserID, m.UserID) {
+			continue
+		}
+		if f.Right != nil && *f.Right != m.Right {
+			continue
+		}
+		m := m
+		members = append(members, &m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return storage.MemberKey(members[i]).Less(storage.MemberKey(members[j]))
+	})
+	lo, hi, err := page(2, len(members), func(i int) storage.Key { return storage.MemberKey(members[i]) }, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	return members[lo:hi], nil
+}
+
+// check expects r.mu to be held.
+func (r *memberRepo) check(m *model.Member) error {
+	if err := r.checkUser(m.UserID); err != nil {
+		return err
+	}
+	if err := r.checkSection(m.SectionID); err != nil {
+		return err
+	}
+	for _, o := range r.members {
+		if o.ID != m.ID && o.SectionID == m.SectionID && o.UserID == m.UserID {
+			return conflict("user %s is already a member of section %s", m.UserID, m.SectionID)
+		}
+	}
+	return nil
+}
+
+func (r *memberRepo) Create(ctx context.Context, m *model.Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m.ID = newID()
+	if err := r.check(m); err != nil {
+		return err
+	}
+	r.members[m.ID] = *m
+	return nil
+}
+
+func (r *memberRepo) Update(ctx context.Context, m *model.Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.members[m.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if err := r.check(m); err != nil {
+		return err
+	}
+	r.members[m.ID] = *m
+	return nil
+}
+
+func (r *memberRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.members[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.members, id)
+	return nil
+}
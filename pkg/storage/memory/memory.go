@@ -0,0 +1,272 @@
+// Package memory implements the storage repositories in process memory.
+// It mirrors the constraints of the SQL schema, including uniqueness,
+// foreign keys and cascading deletes, and is meant for tests and demos.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/google/uuid"
+)
+
+type db struct {
+	// mu is a sync.RWMutex, or does nothing within Atomic, which holds
+	// the mutex for the whole transaction.
+	mu rwLocker
+
+	users         map[string]model.User
+	organizations map[string]model.Organization
+	sections      map[string]model.Section
+	members       map[string]model.Member
+	events        map[string]model.Event
+	comments      map[string]model.Comment
+	attendees     map[string]model.Attendee
+	invites       map[string]model.Invite
+	refreshTokens map[string]storage.RefreshToken
+}
+
+// New returns an empty store.
+func New() *storage.Store {
+	db := &db{
+		mu:            &sync.RWMutex{},
+		users:         map[string]model.User{},
+		organizations: map[string]model.Organization{},
+		sections:      map[string]model.Section{},
+		members:       map[string]model.Member{},
+		events:        map[string]model.Event{},
+		comments:      map[string]model.Comment{},
+		attendees:     map[string]model.Attendee{},
+		invites:       map[string]model.Invite{},
+		refreshTokens: map[string]storage.RefreshToken{},
+	}
+	return db.store()
+}
+
+func (db *db) store() *storage.Store {
+	return &storage.Store{
+		Users:         &userRepo{db},
+		Organizations: &organizationRepo{db},
+		Sections:      &sectionRepo{db},
+		Members:       &memberRepo{db},
+		Events:        &eventRepo{db},
+		Comments:      &commentRepo{db},
+		Attendees:     &attendeeRepo{db},
+		Invites:       &inviteRepo{db},
+		RefreshTokens: &refreshTokenRepo{db},
+		Transactor:    db,
+	}
+}
+
+type rwLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
+// Atomic runs fn with the store locked, and puts the tables back the way
+// they were if fn fails. Within Atomic, fn becomes part of it.
+func (db *db) Atomic(ctx context.Context, fn func(*storage.Store) error) error {
+	if _, ok := db.mu.(noLock); ok {
+		return fn(db.store())
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved := db.copy()
+	tx := *db
+	tx.mu = noLock{}
+	if err := fn(tx.store()); err != nil {
+		*db = *saved
+		return err
+	}
+	return nil
+}
+
+// copy returns a copy of db with copies of its tables.
+func (db *db) copy() *db {
+	c := *db
+	c.users = make(map[string]model.User, len(db.users))
+	for k, v := range db.users {
+		c.users[k] = v
+	}
+	c.organizations = make(map[string]model.Organization, len(db.organizations))
+	for k, v := range db.organizations {
+		c.organizations[k] = v
+	}
+	c.sections = make(map[string]model.Section, len(db.sections))
+	for k, v := range db.sections {
+		c.sections[k] = v
+	}
+	c.members = make(map[string]model.Member, len(db.members))
+	for k, v := range db.members {
+		c.members[k] = v
+	}
+	c.events = make(map[string]model.Event, len(db.events))
+	for k, v := range db.events {
+		c.events[k] = v
+	}
+	c.comments = make(map[string]model.Comment, len(db.comments))
+	for k, v := range db.comments {
+		c.comments[k] = v
+	}
+	c.attendees = make(map[string]model.Attendee, len(db.attendees))
+	for k, v := range db.attendees {
+		c.attendees[k] = v
+	}
+	c.invites = make(map[string]model.Invite, len(db.invites))
+	for k, v := range db.invites {
+		c.invites[k] = v
+	}
+	c.refreshTokens = make(map[string]storage.RefreshToken, len(db.refreshTokens))
+	for k, v := range db.refreshTokens {
+		c.refreshTokens[k] = v
+	}
+	return &c
+}
+
+func newID() string {
+	return uuid.New().String()
+}
+
+func conflict(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", storage.ErrConflict, fmt.Sprintf(format, args...))
+}
+
+// The reference checks expect db.mu to be held.
+
+func (db *db) checkUser(id string) error {
+	if _, ok := db.users[id]; !ok {
+		return conflict("user %s does not exist", id)
+	}
+	return nil
+}
+
+func (db *db) checkSection(id string) error {
+	if _, ok := db.sections[id]; !ok {
+		return conflict("section %s does not exist", id)
+	}
+	return nil
+}
+
+func (db *db) checkEvent(id string) error {
+	if _, ok := db.events[id]; !ok {
+		return conflict("event %s does not exist", id)
+	}
+	return nil
+}
+
+// The cascade helpers expect db.mu to be held for writing.
+
+func (db *db) deleteSection(id string) {
+	delete(db.sections, id)
+	for k, m := range db.members {
+		if m.SectionID == id {
+			delete(db.members, k)
+		}
+	}
+	for k, i := range db.invites {
+		if i.SectionID == id {
+			delete(db.invites, k)
+		}
+	}
+	for k, e := range db.events {
+		for j, s := range e.SectionIDs {
+			if s == id {
+				e.SectionIDs = append(e.SectionIDs[:j:j], e.SectionIDs[j+1:]...)
+				db.events[k] = e
+				break
+			}
+		}
+	}
+}
+
+func (db *db) deleteEvent(id string) {
+	delete(db.events, id)
+	for k, c := range db.comments {
+		if c.EventID == id {
+			delete(db.comments, k)
+		}
+	}
+	for k, a := range db.attendees {
+		if a.EventID == id {
+			delete(db.attendees, k)
+		}
+	}
+	for k, e := range db.events {
+		if e.SeriesID != nil && *e.SeriesID == id {
+			db.deleteEvent(k)
+		}
+	}
+}
+
+func matches(filter *string, v string) bool {
+	return filter == nil || *filter == v
+}
+
+// matchesAny is matches for filters on a set of values, which a nil set
+// does not restrict.
+func matchesAny(filter []string, v string) bool {
+	if filter == nil {
+		return true
+	}
+	for _, f := range filter {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNullable is matches for nullable columns, which no filter value
+// matches when NULL.
+func matchesNullable(filter *string, v *string) bool {
+	return filter == nil || v != nil && *filter == *v
+}
+
+// sameTime reports whether two nullable times are equal.
+func sameTime(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
+
+// page returns the bounds of the items of a list that p selects, given
+// the number of columns of its keys, the number of items and their keys
+// in order. A nil p selects them all.
+func page(width, n int, key func(i int) storage.Key, p *storage.Page) (int, int, error) {
+	lo, hi := 0, n
+	if p == nil {
+		return lo, hi, nil
+	}
+	if p.After != nil && len(p.After) != width || p.Before != nil && len(p.Before) != width {
+		return 0, 0, storage.ErrInvalidKey
+	}
+	if p.After != nil {
+		lo = sort.Search(n, func(i int) bool { return p.After.Less(key(i)) })
+	}
+	if p.Before != nil {
+		hi = sort.Search(n, func(i int) bool { return !key(i).Less(p.Before) })
+	}
+	if hi < lo {
+		hi = lo
+	}
+	if p.Limit > 0 && hi-lo > p.Limit {
+		if p.Last {
+			lo = hi - p.Limit
+		} else {
+			hi = lo + p.Limit
+		}
+	}
+	return lo, hi, nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type organizationRepo struct {
+	*db
+}
+
+func (r *organizationRepo) Get(ctx context.Context, id string) (*model.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.organizations[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &o, nil
+}
+
+func (r *organizationRepo) GetMany(ctx context.Context, ids []string) ([]*model.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var organizations []*model.Organization
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		o, ok := r.organizations[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		organizations = append(organizations, &o)
+	}
+	return organizations, nil
+}
+
+func (r *organizationRepo) Create(ctx context.Context, o *model.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o.ID = newID()
+	r.organizations[o.ID] = *o
+	return nil
+}
+
+func (r *organizationRepo) Update(ctx context.Context, o *model.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.organizations[o.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	r.organizations[o.ID] = *o
+	return nil
+}
+
+func (r *organizationRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.organizations[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(r.organizations, id)
+	for k, s := range r.sections {
+		if s.OrganizationID == id {
+			r.deleteSection(k)
+		}
+	}
+	for k, e := range r.events {
+		if e.OrganizationID != nil && *e.OrganizationID == id {
+			r.deleteEvent(k)
+		}
+	}
+	return nil
+}
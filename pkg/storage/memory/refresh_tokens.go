@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type refreshTokenRepo struct {
+	*db
+}
+
+func (r *refreshTokenRepo) GetByHash(ctx context.Context, hash string) (*storage.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.refreshTokens {
+		if t.Hash == hash {
+			return &t, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *refreshTokenRepo) Create(ctx context.Context, t *storage.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUser(t.UserID); err != nil {
+		return err
+	}
+	for _, o := range r.refreshTokens {
+		if o.Hash == t.Hash {
+			return conflict("refresh token exists")
+		}
+	}
+	t.ID = newID()
+	r.refreshTokens[t.ID] = *t
+	return nil
+}
+
+func (r *refreshTokenRepo) MarkUsed(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.refreshTokens[id]
+	if !ok || t.Used {
+		return storage.ErrNotFound
+	}
+	t.Used = true
+	r.refreshTokens[id] = t
+	return nil
+}
+
+func (r *refreshTokenRepo) RevokeFamily(ctx context.Context, family string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, t := range r.refreshTokens {
+		if t.Family == family {
+			t.Revoked = true
+			r.refreshTokens[k] = t
+		}
+	}
+	return nil
+}
+
+func (r *refreshTokenRepo) RevokeUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, t := range r.refreshTokens {
+		if t.UserID == userID {
+			t.Revoked = true
+			r.refreshTokens[k] = t
+		}
+	}
+	return nil
+}
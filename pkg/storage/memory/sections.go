@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type sectionRepo struct {
+	*db
+}
+
+func (r *sectionRepo) Get(ctx context.Context, id string) (*model.Section, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.sections[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *sectionRepo) GetMany(ctx context.Context, ids []string) ([]*model.Section, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sections []*model.Section
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		s, ok := r.sections[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		sections = append(sections, &s)
+	}
+	return sections, nil
+}
+
+func (r *sectionRepo) ListByOrganization(ctx context.Context, organizationID string, p *storage.Page) ([]*model.Section, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sections []*model.Section
+	for _, s := range r.sections {
+		if s.OrganizationID == organizationID {
+			s := s
+			sections = append(sections, &s)
+		}
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return storage.SectionKey(sections[i]).Less(storage.SectionKey(sections[j]))
+	})
+	lo, hi, err := page(2, len(sections), func(i int) storage.Key { return storage.SectionKey(sections[i]) }, p)
+	if err != nil {
+		return nil, err
+	}
+	return sections[lo:hi], nil
+}
+
+// checkRefs expects r.mu to be held.
+func (r *sectionRepo) checkRefs(s *model.Section) error {
+	if _, ok := r.organizations[s.OrganizationID]; !ok {
+		return conflict("organization %s does not exist", s.OrganizationID)
+	}
+	return nil
+}
+
+func (r *sectionRepo) Create(ctx context.Context, s *model.Section) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkRefs(s); err != nil {
+		return err
+	}
+	s.ID = newID()
+	r.sections[s.ID] = *s
+	return nil
+}
+
+func (r *sectionRepo) Update(ctx context.Context, s *model.Section) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sections[s.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if err := r.checkRefs(s); err != nil {
+		return err
+	}
+	r.sections[s.ID] = *s
+	return nil
+}
+
+func (r *sectionRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sections[id]; !ok {
+		return storage.ErrNotFound
+	}
+	r.deleteSection(id)
+	return nil
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+type userRepo struct {
+	*db
+}
+
+func (r *userRepo) Get(ctx context.Context, id string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *userRepo) GetMany(ctx context.Context, ids []string) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []*model.User
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		u, ok := r.users[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		users = append(users, &u)
+	}
+	return users, nil
+}
+
+func (r *userRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *userRepo) GetByCalendarTokenHash(ctx context.Context, hash string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.CalendarTokenHash != nil && *u.CalendarTokenHash == hash {
+			return &u, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// checkUnique expects r.mu to be held.
+func (r *userRepo) checkUnique(u *model.User) error {
+	for _, o := range r.users {
+		if o.ID == u.ID {
+			continue
+		}
+		if o.Username == u.Username {
+			return conflict("username %s is taken", u.Username)
+		}
+		if o.Email == u.Email {
+			return conflict("email %s is taken", u.Email)
+		}
+		if o.CalendarTokenHash != nil && u.CalendarTokenHash != nil && *o.CalendarTokenHash == *u.CalendarTokenHash {
+			return conflict("calendar token is taken")
+		}
+	}
+	return nil
+}
+
+func (r *userRepo) Create(ctx context.Context, u *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u.ID = newID()
+	if err := r.checkUnique(u); err != nil {
+		return err
+	}
+	r.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) Update(ctx context.Context, u *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.ID]; !ok {
+		return storage.ErrNotFound
+	}
+	if err := r.checkUnique(u); err != nil {
+		return err
+	}
+	r.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return storage.ErrNotFound
+	}
+	for _, e := range r.events {
+		if e.CreatorID == id {
+			return conflict("user %s has created events", id)
+		}
+	}
+
+	delete(r.users, id)
+	for k, m := range r.members {
+		if m.UserID == id {
+			delete(r.members, k)
+		}
+	}
+	for k, c := range r.comments {
+		if c.CreatorID == id {
+			delete(r.comments, k)
+		}
+	}
+	for k, a := range r.attendees {
+		if a.UserID == id {
+			delete(r.attendees, k)
+		}
+	}
+	for k, i := range r.invites {
+		if i.UserID != nil && *i.UserID == id {
+			delete(r.invites, k)
+			continue
+		}
+		if i.InvitedByID != nil && *i.InvitedByID == id {
+			i.InvitedByID = nil
+			r.invites[k] = i
+		}
+	}
+	for k, t := range r.refreshTokens {
+		if t.UserID == id {
+			delete(r.refreshTokens, k)
+		}
+	}
+	return nil
+}
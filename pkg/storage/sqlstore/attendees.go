@@ -0,0 +1,108 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const attendeeColumns = "id, user_id, event_id, occurrence, commitment, comment"
+
+type attendeeRepo struct {
+	db conn
+}
+
+func scanAttendee(row scanner) (*model.Attendee, error) {
+	var a model.Attendee
+	if err := row.Scan(&a.ID, &a.UserID, &a.EventID, nullTimeScanner{&a.Occurrence}, &a.Commitment, &a.Comment); err != nil {
+		return nil, translate(err)
+	}
+	return &a, nil
+}
+
+func (r *attendeeRepo) Get(ctx context.Context, id string) (*model.Attendee, error) {
+	return scanAttendee(r.db.QueryRowContext(ctx,
+		"SELECT "+attendeeColumns+" FROM attendees WHERE id = $1", id))
+}
+
+func (r *attendeeRepo) GetByEventUser(ctx context.Context, eventID, userID string, occurrence *time.Time) (*model.Attendee, error) {
+	if occurrence == nil {
+		return scanAttendee(r.db.QueryRowContext(ctx,
+			"SELECT "+attendeeColumns+" FROM attendees WHERE event_id = $1 AND user_id = $2 AND occurrence IS NULL", eventID, userID))
+	}
+	return scanAttendee(r.db.QueryRowContext(ctx,
+		"SELECT "+attendeeColumns+" FROM attendees WHERE event_id = $1 AND user_id = $2 AND occurrence = $3",
+		eventID, userID, timeValue(*occurrence)))
+}
+
+// attendeeKeys are the columns of storage.AttendeeKey.
+var attendeeKeys = []keyColumn{{name: "event_id"}, {name: "occurrence", nullable: true, time: true}, {name: "user_id"}}
+
+// attendeeWhere returns the conditions of f but for its page.
+func attendeeWhere(f storage.AttendeeFilter) where {
+	var w where
+	if f.EventID != nil {
+		w.add("event_id = $%d", *f.EventID)
+	}
+	if f.EventIDs != nil {
+		w.in("event_id", f.EventIDs)
+	}
+	if f.UserID != nil {
+		w.add("user_id = $%d", *f.UserID)
+	}
+	if f.Occurrence != nil {
+		w.add("occurrence = $%d", timeValue(*f.Occurrence))
+	}
+	if f.Commitment != nil {
+		w.add("commitment = $%d", *f.Commitment)
+	}
+	return w
+}
+
+func (r *attendeeRepo) List(ctx context.Context, f storage.AttendeeFilter) ([]*model.Attendee, error) {
+	w := attendeeWhere(f)
+	query, err := w.list(attendeeColumns, "attendees", attendeeKeys, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var attendees []*model.Attendee
+	for rows.Next() {
+		a, err := scanAttendee(rows)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, a)
+	}
+	return attendees, rows.Err()
+}
+
+func (r *attendeeRepo) Count(ctx context.Context, f storage.AttendeeFilter) (int, error) {
+	w := attendeeWhere(f)
+	return w.count(ctx, r.db, "attendees")
+}
+
+func (r *attendeeRepo) Create(ctx context.Context, a *model.Attendee) error {
+	a.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO attendees ("+attendeeColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		a.ID, a.UserID, a.EventID, nullTimeValue(a.Occurrence), a.Commitment, a.Comment)
+	return translate(err)
+}
+
+func (r *attendeeRepo) Update(ctx context.Context, a *model.Attendee) error {
+	return exec(ctx, r.db,
+		"UPDATE attendees SET event_id = $2, occurrence = $3, commitment = $4, comment = $5 WHERE id = $1",
+		a.ID, a.EventID, nullTimeValue(a.Occurrence), a.Commitment, a.Comment)
+}
+
+func (r *attendeeRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM attendees WHERE id = $1", id)
+}
@@ -0,0 +1,80 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const commentColumns = "id, text, creator_id, event_id, created_at"
+
+type commentRepo struct {
+	db conn
+}
+
+func scanComment(row scanner) (*model.Comment, error) {
+	var c model.Comment
+	if err := row.Scan(&c.ID, &c.Text, &c.CreatorID, &c.EventID, timeScanner{&c.CreatedAt}); err != nil {
+		return nil, translate(err)
+	}
+	return &c, nil
+}
+
+func (r *commentRepo) Get(ctx context.Context, id string) (*model.Comment, error) {
+	return scanComment(r.db.QueryRowContext(ctx,
+		"SELECT "+commentColumns+" FROM comments WHERE id = $1", id))
+}
+
+// commentKeys are the columns of storage.CommentKey.
+var commentKeys = []keyColumn{{name: "created_at", time: true}, {name: "id"}}
+
+func (r *commentRepo) ListByEvent(ctx context.Context, eventID string, p *storage.Page) ([]*model.Comment, error) {
+	var w where
+	w.add("event_id = $%d", eventID)
+	query, err := w.list(commentColumns, "comments", commentKeys, p)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var comments []*model.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *commentRepo) CountByEvent(ctx context.Context, eventID string) (int, error) {
+	var w where
+	w.add("event_id = $%d", eventID)
+	return w.count(ctx, r.db, "comments")
+}
+
+func (r *commentRepo) Create(ctx context.Context, c *model.Comment) error {
+	c.ID = newID()
+	c.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO comments ("+commentColumns+") VALUES ($1, $2, $3, $4, $5)",
+		c.ID, c.Text, c.CreatorID, c.EventID, timeValue(c.CreatedAt))
+	return translate(err)
+}
+
+func (r *commentRepo) Update(ctx context.Context, c *model.Comment) error {
+	return exec(ctx, r.db,
+		"UPDATE comments SET text = $2 WHERE id = $1",
+		c.ID, c.Text)
+}
+
+func (r *commentRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM comments WHERE id = $1", id)
+}
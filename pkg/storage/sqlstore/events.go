@@ -0,0 +1,230 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const eventColumns = `id, name, description, adress, start, "end", creator_id, organization_id, recurrence, exdates, series_id, recurrence_id, uid`
+
+type eventRepo struct {
+	db conn
+}
+
+func scanEvent(row scanner) (*model.Event, error) {
+	var e model.Event
+	err := row.Scan(&e.ID, &e.Name, &e.Description, &e.Adress, timeScanner{&e.Start}, nullTimeScanner{&e.End}, &e.CreatorID, &e.OrganizationID,
+		&e.Recurrence, timeListScanner{&e.ExDates}, &e.SeriesID, nullTimeScanner{&e.RecurrenceID}, &e.UID)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &e, nil
+}
+
+func (r *eventRepo) Get(ctx context.Context, id string) (*model.Event, error) {
+	e, err := scanEvent(r.db.QueryRowContext(ctx,
+		"SELECT "+eventColumns+" FROM events WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadSections(ctx, []*model.Event{e}, "event_id = $1", id); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (r *eventRepo) GetMany(ctx context.Context, ids []string) ([]*model.Event, error) {
+	var w where
+	w.in("id", ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+eventColumns+" FROM events"+w.String(), w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var events []*model.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.loadSections(ctx, events, "event_id IN (SELECT id FROM events"+w.String()+")", w.args...)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// eventKeys are the columns of storage.EventKey.
+var eventKeys = []keyColumn{{name: "start", time: true}, {name: "id"}}
+
+// eventWhere returns the conditions of f but for its page.
+func eventWhere(f storage.EventFilter) where {
+	var w where
+	if f.OrganizationID != nil {
+		w.add("organization_id = $%d", *f.OrganizationID)
+	}
+	if f.SeriesID != nil {
+		w.add("series_id = $%d", *f.SeriesID)
+	}
+	if f.SeriesIDs != nil {
+		w.in("series_id", f.SeriesIDs)
+	}
+	if f.UID != nil {
+		w.add("uid = $%d", *f.UID)
+	}
+	if f.Recurring != nil && *f.Recurring {
+		w.conds = append(w.conds, "recurrence IS NOT NULL")
+	}
+	if f.Recurring != nil && !*f.Recurring {
+		w.conds = append(w.conds, "recurrence IS NULL")
+	}
+	if f.Start != nil {
+		w.add("(start >= $%d OR recurrence IS NOT NULL)", timeValue(*f.Start))
+	}
+	if f.End != nil {
+		w.add("start <= $%d", timeValue(*f.End))
+	}
+	return w
+}
+
+func (r *eventRepo) List(ctx context.Context, f storage.EventFilter) ([]*model.Event, error) {
+	w := eventWhere(f)
+	query, err := w.list(eventColumns, "events", eventKeys, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var events []*model.Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The sections of a page are looked up by the IDs of its events, as
+	// the conditions of the page select the events beyond it too.
+	cond, args := "event_id IN (SELECT id FROM events"+w.String()+")", w.args
+	if f.Page != nil && f.Page.Limit > 0 {
+		ids := make([]string, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+		}
+		var in where
+		in.in("event_id", ids)
+		cond, args = in.conds[0], in.args
+	}
+	if err := r.loadSections(ctx, events, cond, args...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *eventRepo) Count(ctx context.Context, f storage.EventFilter) (int, error) {
+	w := eventWhere(f)
+	return w.count(ctx, r.db, "events")
+}
+
+// loadSections fills in the SectionIDs of events from the event_sections
+// rows matching cond.
+func (r *eventRepo) loadSections(ctx context.Context, events []*model.Event, cond string, args ...interface{}) error {
+	if len(events) == 0 {
+		return nil
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT event_id, section_id FROM event_sections WHERE "+cond+" ORDER BY section_id", args...)
+	if err != nil {
+		return translate(err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*model.Event, len(events))
+	for _, e := range events {
+		byID[e.ID] = e
+	}
+	for rows.Next() {
+		var eventID, sectionID string
+		if err := rows.Scan(&eventID, &sectionID); err != nil {
+			return err
+		}
+		if e, ok := byID[eventID]; ok {
+			e.SectionIDs = append(e.SectionIDs, sectionID)
+		}
+	}
+	return rows.Err()
+}
+
+// saveSections replaces the event_sections rows of e.
+func saveSections(ctx context.Context, tx conn, e *model.Event) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM event_sections WHERE event_id = $1", e.ID); err != nil {
+		return err
+	}
+	for _, id := range e.SectionIDs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO event_sections (event_id, section_id) VALUES ($1, $2)", e.ID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *eventRepo) Create(ctx context.Context, e *model.Event) error {
+	e.ID = newID()
+	err := inTx(ctx, r.db, func(tx conn) error {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO events ("+eventColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+			e.ID, e.Name, e.Description, e.Adress, timeValue(e.Start), nullTimeValue(e.End), e.CreatorID, e.OrganizationID,
+			e.Recurrence, timeListValue(e.ExDates), e.SeriesID, nullTimeValue(e.RecurrenceID), e.UID)
+		if err != nil {
+			return err
+		}
+		return saveSections(ctx, tx, e)
+	})
+	return translate(err)
+}
+
+func (r *eventRepo) Update(ctx context.Context, e *model.Event) error {
+	err := inTx(ctx, r.db, func(tx conn) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE events SET name = $2, description = $3, adress = $4, start = $5, "end" = $6, creator_id = $7, organization_id = $8,
+				recurrence = $9, exdates = $10, series_id = $11, recurrence_id = $12, uid = $13 WHERE id = $1`,
+			e.ID, e.Name, e.Description, e.Adress, timeValue(e.Start), nullTimeValue(e.End), e.CreatorID, e.OrganizationID,
+			e.Recurrence, timeListValue(e.ExDates), e.SeriesID, nullTimeValue(e.RecurrenceID), e.UID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return storage.ErrNotFound
+		}
+		return saveSections(ctx, tx, e)
+	})
+	return translate(err)
+}
+
+func (r *eventRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM events WHERE id = $1", id)
+}
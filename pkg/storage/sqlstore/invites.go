@@ -0,0 +1,90 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const inviteColumns = `id, user_id, email, section_id, invited_by_id, "right", expires_at, token_hash`
+
+type inviteRepo struct {
+	db conn
+}
+
+func scanInvite(row scanner) (*model.Invite, error) {
+	var (
+		i       model.Invite
+		expires *int64
+	)
+	if err := row.Scan(&i.ID, &i.UserID, &i.Email, &i.SectionID, &i.InvitedByID, &i.Right, &expires, &i.TokenHash); err != nil {
+		return nil, translate(err)
+	}
+	if expires != nil {
+		t := time.Unix(*expires, 0).UTC()
+		i.ExpiresAt = &t
+	}
+	return &i, nil
+}
+
+func (r *inviteRepo) Get(ctx context.Context, id string) (*model.Invite, error) {
+	return scanInvite(r.db.QueryRowContext(ctx,
+		"SELECT "+inviteColumns+" FROM invites WHERE id = $1", id))
+}
+
+func (r *inviteRepo) GetByTokenHash(ctx context.Context, hash string) (*model.Invite, error) {
+	return scanInvite(r.db.QueryRowContext(ctx,
+		"SELECT "+inviteColumns+" FROM invites WHERE token_hash = $1", hash))
+}
+
+// inviteKeys are the columns of storage.InviteKey.
+var inviteKeys = []keyColumn{{name: "section_id"}, {name: "id"}}
+
+func (r *inviteRepo) List(ctx context.Context, f storage.InviteFilter) ([]*model.Invite, error) {
+	var w where
+	if f.SectionID != nil {
+		w.add("section_id = $%d", *f.SectionID)
+	}
+	if f.UserID != nil {
+		w.add("user_id = $%d", *f.UserID)
+	}
+
+	query, err := w.list(inviteColumns, "invites", inviteKeys, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var invites []*model.Invite
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+func (r *inviteRepo) Create(ctx context.Context, i *model.Invite) error {
+	var expires *int64
+	if i.ExpiresAt != nil {
+		unix := i.ExpiresAt.Unix()
+		expires = &unix
+	}
+	i.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO invites ("+inviteColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		i.ID, i.UserID, i.Email, i.SectionID, i.InvitedByID, i.Right, expires, i.TokenHash)
+	return translate(err)
+}
+
+func (r *inviteRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM invites WHERE id = $1", id)
+}
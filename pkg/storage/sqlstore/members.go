@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const memberColumns = `id, user_id, section_id, "right"`
+
+type memberRepo struct {
+	db conn
+}
+
+func scanMember(row scanner) (*model.Member, error) {
+	var m model.Member
+	if err := row.Scan(&m.ID, &m.UserID, &m.SectionID, &m.Right); err != nil {
+		return nil, translate(err)
+	}
+	return &m, nil
+}
+
+func (r *memberRepo) Get(ctx context.Context, id string) (*model.Member, error) {
+	return scanMember(r.db.QueryRowContext(ctx,
+		"SELECT "+memberColumns+" FROM members WHERE id = $1", id))
+}
+
+func (r *memberRepo) GetBySectionUser(ctx context.Context, sectionID, userID string) (*model.Member, error) {
+	return scanMember(r.db.QueryRowContext(ctx,
+		"SELECT "+memberColumns+" FROM members WHERE section_id = $1 AND user_id = $2", sectionID, userID))
+}
+
+// memberKeys are the columns of storage.MemberKey.
+var memberKeys = []keyColumn{{name: "section_id"}, {name: "user_id"}}
+
+func (r *memberRepo) List(ctx context.Context, f storage.MemberFilter) ([]*model.Member, error) {
+	var w where
+	if f.SectionID != nil {
+		w.add("section_id = $%d", *f.SectionID)
+	}
+	if f.SectionIDs != nil {
+		w.in("section_id", f.SectionIDs)
+	}
+	if f.UserID != nil {
+		w.add("user_id = $%d", *f.UserID)
+	}
+	if f.Right != nil {
+		w.add(`"right" = $%d`, *f.Right)
+	}
+
+	query, err := w.list(memberColumns, "members", memberKeys, f.Page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var members []*model.Member
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (r *memberRepo) Create(ctx context.Context, m *model.Member) error {
+	m.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO members ("+memberColumns+") VALUES ($1, $2, $3, $4)",
+		m.ID, m.UserID, m.SectionID, m.Right)
+	return translate(err)
+}
+
+func (r *memberRepo) Update(ctx context.Context, m *model.Member) error {
+	return exec(ctx, r.db,
+		`UPDATE members SET user_id = $2, section_id = $3, "right" = $4 WHERE id = $1`,
+		m.ID, m.UserID, m.SectionID, m.Right)
+}
+
+func (r *memberRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM members WHERE id = $1", id)
+}
@@ -0,0 +1,199 @@
+package sqlstore_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/sqlstore"
+)
+
+// tablesQueries list the tables of the database of each driver.
+var tablesQueries = map[string]string{
+	"sqlite":   "SELECT name FROM sqlite_master WHERE type = 'table'",
+	"postgres": "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()",
+}
+
+// schema returns the columns of each table of db, sorted by name.
+func schema(t *testing.T, driver string, db *sqlstore.DB) map[string][]string {
+	t.Helper()
+	rows, err := db.Query(tablesQueries[driver])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := map[string][]string{}
+	for _, table := range tables {
+		rows, err := db.Query("SELECT * FROM " + table + " LIMIT 0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns, err := rows.Columns()
+		rows.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(columns)
+		s[table] = columns
+	}
+	return s
+}
+
+func applied(t *testing.T, db *sqlstore.DB) []int {
+	t.Helper()
+	status, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, s := range status {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+// TestMigrations migrates all the way up and down, one migration at a
+// time, and checks that each migration reverts to the schema it started
+// from.
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	for driver, db := range openDBs(t) {
+		driver, db := driver, db
+		t.Run(driver, func(t *testing.T) {
+			status, err := db.MigrationStatus(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(status) == 0 {
+				t.Fatal("no migrations")
+			}
+			var all []int
+			for _, s := range status {
+				all = append(all, s.Version)
+			}
+			if !sort.IntsAreSorted(all) {
+				t.Errorf("migrations in the order %v", all)
+			}
+			if got := applied(t, db); got != nil {
+				t.Fatalf("applied %v before migrating", got)
+			}
+
+			// before[i] is the schema with the first i migrations applied.
+			before := []map[string][]string{schema(t, driver, db)}
+			for i := range all {
+				// Apply one more migration than before by reverting
+				// every later one.
+				if _, err := db.MigrateUp(ctx); err != nil {
+					t.Fatal(err)
+				}
+				for j := len(all) - 1; j > i; j-- {
+					if _, err := db.MigrateDown(ctx); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if got := applied(t, db); !reflect.DeepEqual(got, all[:i+1]) {
+					t.Fatalf("applied %v, want %v", got, all[:i+1])
+				}
+				before = append(before, schema(t, driver, db))
+			}
+
+			if ms, err := db.MigrateUp(ctx); err != nil || len(ms) != 0 {
+				t.Fatalf("MigrateUp with none pending = %d migrations, %v", len(ms), err)
+			}
+			for i := len(all) - 1; i >= 0; i-- {
+				m, err := db.MigrateDown(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if m == nil || m.Version != all[i] {
+					t.Fatalf("MigrateDown reverted %v, want %d", m, all[i])
+				}
+				if got := schema(t, driver, db); !reflect.DeepEqual(got, before[i]) {
+					t.Errorf("schema after reverting %d_%s:\n got %v\nwant %v", m.Version, m.Name, got, before[i])
+				}
+			}
+			if m, err := db.MigrateDown(ctx); m != nil || err != nil {
+				t.Errorf("MigrateDown with none applied = %v, %v", m, err)
+			}
+		})
+	}
+}
+
+// TestMigrationsWithData migrates down and up again over rows of every
+// table.
+func TestMigrationsWithData(t *testing.T) {
+	ctx := context.Background()
+	for driver, db := range openDBs(t) {
+		db := db
+		t.Run(driver, func(t *testing.T) {
+			if _, err := db.MigrateUp(ctx); err != nil {
+				t.Fatal(err)
+			}
+			store := db.Store()
+			must := func(err error) {
+				t.Helper()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			u := &model.User{Username: "alice", Email: "alice@example.org"}
+			must(store.Users.Create(ctx, u))
+			org := &model.Organization{Name: "Orchestra", Timezone: "Europe/Berlin"}
+			must(store.Organizations.Create(ctx, org))
+			section := &model.Section{Name: "Strings", OrganizationID: org.ID}
+			must(store.Sections.Create(ctx, section))
+			must(store.Members.Create(ctx, &model.Member{UserID: u.ID, SectionID: section.ID, Right: 1}))
+			start := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+			e := &model.Event{
+				Name:           "Rehearsal",
+				Start:          start,
+				CreatorID:      u.ID,
+				OrganizationID: &org.ID,
+				SectionIDs:     []string{section.ID},
+			}
+			must(store.Events.Create(ctx, e))
+			must(store.Comments.Create(ctx, &model.Comment{EventID: e.ID, CreatorID: u.ID, Text: "first"}))
+			must(store.Attendees.Create(ctx, &model.Attendee{UserID: u.ID, EventID: e.ID, Commitment: model.CommitmentYes}))
+			email := "bob@example.org"
+			must(store.Invites.Create(ctx, &model.Invite{Email: &email, SectionID: section.ID}))
+			must(store.Invites.Create(ctx, &model.Invite{UserID: &u.ID, SectionID: section.ID}))
+			must(store.RefreshTokens.Create(ctx, &storage.RefreshToken{
+				UserID:    u.ID,
+				Family:    "family",
+				Hash:      "hash",
+				ExpiresAt: start,
+			}))
+
+			for {
+				m, err := db.MigrateDown(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if m == nil {
+					break
+				}
+			}
+			if _, err := db.MigrateUp(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
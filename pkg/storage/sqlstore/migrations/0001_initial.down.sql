@@ -0,0 +1,8 @@
+DROP TABLE invites;
+DROP TABLE attendees;
+DROP TABLE comments;
+DROP TABLE events;
+DROP TABLE members;
+DROP TABLE sections;
+DROP TABLE organizations;
+DROP TABLE users;
@@ -0,0 +1,69 @@
+-- Databases created before migrations were introduced already hold these
+-- tables, hence IF NOT EXISTS.
+
+CREATE TABLE IF NOT EXISTS users (
+	id        TEXT PRIMARY KEY,
+	username  TEXT NOT NULL UNIQUE,
+	password  TEXT NOT NULL,
+	email     TEXT NOT NULL UNIQUE,
+	showname  TEXT,
+	superuser BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+	id      TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	picture TEXT
+);
+
+CREATE TABLE IF NOT EXISTS sections (
+	id              TEXT PRIMARY KEY,
+	name            TEXT NOT NULL,
+	organization_id TEXT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS members (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	section_id TEXT NOT NULL REFERENCES sections (id) ON DELETE CASCADE,
+	"right"    INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (section_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	description TEXT,
+	adress      TEXT,
+	start       TIMESTAMPTZ NOT NULL,
+	"end"       TIMESTAMPTZ,
+	creator_id  TEXT NOT NULL REFERENCES users (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS events_start_idx ON events (start);
+
+CREATE TABLE IF NOT EXISTS comments (
+	id         TEXT PRIMARY KEY,
+	text       TEXT NOT NULL,
+	creator_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS comments_event_idx ON comments (event_id);
+
+CREATE TABLE IF NOT EXISTS attendees (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+	commitment TEXT NOT NULL,
+	comment    TEXT,
+	UNIQUE (event_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS invites (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	section_id TEXT NOT NULL REFERENCES sections (id) ON DELETE CASCADE,
+	UNIQUE (section_id, user_id)
+);
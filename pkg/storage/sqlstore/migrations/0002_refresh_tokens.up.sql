@@ -0,0 +1,12 @@
+CREATE TABLE refresh_tokens (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	family     TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	-- Unix seconds, which compare the same way in every database.
+	expires_at BIGINT NOT NULL,
+	used       BOOLEAN NOT NULL DEFAULT FALSE,
+	revoked    BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family);
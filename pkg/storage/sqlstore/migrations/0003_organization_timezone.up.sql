@@ -0,0 +1 @@
+ALTER TABLE organizations ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
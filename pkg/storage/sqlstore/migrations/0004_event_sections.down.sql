@@ -0,0 +1,3 @@
+DROP TABLE event_sections;
+DROP INDEX events_organization_idx;
+ALTER TABLE events DROP COLUMN organization_id;
@@ -0,0 +1,15 @@
+-- Events created before events belonged to organizations keep a NULL
+-- organization.
+ALTER TABLE events ADD COLUMN organization_id TEXT REFERENCES organizations (id) ON DELETE CASCADE;
+
+CREATE INDEX events_organization_idx ON events (organization_id);
+
+-- The sections an event is for. Events without rows here are for the
+-- whole organization.
+CREATE TABLE event_sections (
+	event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+	section_id TEXT NOT NULL REFERENCES sections (id) ON DELETE CASCADE,
+	PRIMARY KEY (event_id, section_id)
+);
+
+CREATE INDEX event_sections_section_idx ON event_sections (section_id);
@@ -0,0 +1,14 @@
+-- Invites by email cannot be represented any more and are dropped.
+CREATE TABLE invites_old (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	section_id TEXT NOT NULL REFERENCES sections (id) ON DELETE CASCADE,
+	UNIQUE (section_id, user_id)
+);
+
+INSERT INTO invites_old (id, user_id, section_id)
+	SELECT id, user_id, section_id FROM invites WHERE user_id IS NOT NULL;
+
+DROP TABLE invites;
+
+ALTER TABLE invites_old RENAME TO invites;
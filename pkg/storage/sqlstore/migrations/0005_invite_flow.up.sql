@@ -0,0 +1,25 @@
+-- Invites may now be addressed to an email address instead of a user,
+-- which makes user_id nullable. As that cannot be changed in place the
+-- same way in every database, the table is rebuilt.
+CREATE TABLE invites_new (
+	id            TEXT PRIMARY KEY,
+	section_id    TEXT NOT NULL REFERENCES sections (id) ON DELETE CASCADE,
+	-- Exactly one of user_id and email is set.
+	user_id       TEXT REFERENCES users (id) ON DELETE CASCADE,
+	email         TEXT,
+	-- Hash of the token sent to invites by email.
+	token_hash    TEXT UNIQUE,
+	invited_by_id TEXT REFERENCES users (id) ON DELETE SET NULL,
+	"right"       INTEGER NOT NULL DEFAULT 1,
+	-- Unix seconds, NULL for invites created before invites expired.
+	expires_at    BIGINT,
+	UNIQUE (section_id, user_id),
+	UNIQUE (section_id, email)
+);
+
+INSERT INTO invites_new (id, section_id, user_id)
+	SELECT id, section_id, user_id FROM invites;
+
+DROP TABLE invites;
+
+ALTER TABLE invites_new RENAME TO invites;
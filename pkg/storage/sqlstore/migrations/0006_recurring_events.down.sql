@@ -0,0 +1,26 @@
+-- Responses to occurrences and the events replacing occurrences cannot be
+-- represented any more and are dropped. Recurring events become single
+-- events at the start of their first occurrence.
+DELETE FROM events WHERE series_id IS NOT NULL;
+
+CREATE TABLE attendees_old (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+	commitment TEXT NOT NULL,
+	comment    TEXT,
+	UNIQUE (event_id, user_id)
+);
+
+INSERT INTO attendees_old (id, user_id, event_id, commitment, comment)
+	SELECT id, user_id, event_id, commitment, comment FROM attendees WHERE occurrence IS NULL;
+
+DROP TABLE attendees;
+
+ALTER TABLE attendees_old RENAME TO attendees;
+
+DROP INDEX events_series_idx;
+ALTER TABLE events DROP COLUMN recurrence_id;
+ALTER TABLE events DROP COLUMN series_id;
+ALTER TABLE events DROP COLUMN exdates;
+ALTER TABLE events DROP COLUMN recurrence;
@@ -0,0 +1,32 @@
+-- Recurring events carry an RFC 5545 recurrence rule and the starts of the
+-- occurrences removed from them, as a comma separated list of UTC times
+-- like EXDATE. Single occurrences are replaced by events naming the series
+-- and the original start of the occurrence.
+ALTER TABLE events ADD COLUMN recurrence TEXT;
+ALTER TABLE events ADD COLUMN exdates TEXT;
+ALTER TABLE events ADD COLUMN series_id TEXT REFERENCES events (id) ON DELETE CASCADE;
+ALTER TABLE events ADD COLUMN recurrence_id TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX events_series_idx ON events (series_id, recurrence_id);
+
+-- Responses to recurring events are per occurrence. The attendees table is
+-- rebuilt to replace its unique constraint.
+CREATE TABLE attendees_new (
+	id         TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	event_id   TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+	-- Start of the occurrence of a recurring event, NULL for single events.
+	occurrence TIMESTAMPTZ,
+	commitment TEXT NOT NULL,
+	comment    TEXT
+);
+
+INSERT INTO attendees_new (id, user_id, event_id, commitment, comment)
+	SELECT id, user_id, event_id, commitment, comment FROM attendees;
+
+DROP TABLE attendees;
+
+ALTER TABLE attendees_new RENAME TO attendees;
+
+CREATE UNIQUE INDEX attendees_event_user_idx ON attendees (event_id, user_id) WHERE occurrence IS NULL;
+CREATE UNIQUE INDEX attendees_occurrence_user_idx ON attendees (event_id, occurrence, user_id) WHERE occurrence IS NOT NULL;
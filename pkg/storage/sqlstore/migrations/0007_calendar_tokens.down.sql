@@ -0,0 +1,2 @@
+DROP INDEX users_calendar_token_idx;
+ALTER TABLE users DROP COLUMN calendar_token_hash;
@@ -0,0 +1,5 @@
+-- Hash of the token the user's calendar feeds are fetched with, NULL while
+-- the user has none.
+ALTER TABLE users ADD COLUMN calendar_token_hash TEXT;
+
+CREATE UNIQUE INDEX users_calendar_token_idx ON users (calendar_token_hash);
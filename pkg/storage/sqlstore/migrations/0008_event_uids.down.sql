@@ -0,0 +1,2 @@
+DROP INDEX events_uid_idx;
+ALTER TABLE events DROP COLUMN uid;
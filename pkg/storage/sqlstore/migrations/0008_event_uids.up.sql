@@ -0,0 +1,5 @@
+-- UID of the iCalendar event an event was imported from. Importing the
+-- same calendar again skips the events already imported.
+ALTER TABLE events ADD COLUMN uid TEXT;
+
+CREATE UNIQUE INDEX events_uid_idx ON events (organization_id, uid);
@@ -0,0 +1,65 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+const organizationColumns = "id, name, picture, timezone"
+
+type organizationRepo struct {
+	db conn
+}
+
+func scanOrganization(row scanner) (*model.Organization, error) {
+	var o model.Organization
+	if err := row.Scan(&o.ID, &o.Name, &o.Picture, &o.Timezone); err != nil {
+		return nil, translate(err)
+	}
+	return &o, nil
+}
+
+func (r *organizationRepo) Get(ctx context.Context, id string) (*model.Organization, error) {
+	return scanOrganization(r.db.QueryRowContext(ctx,
+		"SELECT "+organizationColumns+" FROM organizations WHERE id = $1", id))
+}
+
+func (r *organizationRepo) GetMany(ctx context.Context, ids []string) ([]*model.Organization, error) {
+	var w where
+	w.in("id", ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+organizationColumns+" FROM organizations"+w.String(), w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var organizations []*model.Organization
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, o)
+	}
+	return organizations, rows.Err()
+}
+
+func (r *organizationRepo) Create(ctx context.Context, o *model.Organization) error {
+	o.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO organizations ("+organizationColumns+") VALUES ($1, $2, $3, $4)",
+		o.ID, o.Name, o.Picture, o.Timezone)
+	return translate(err)
+}
+
+func (r *organizationRepo) Update(ctx context.Context, o *model.Organization) error {
+	return exec(ctx, r.db,
+		"UPDATE organizations SET name = $2, picture = $3, timezone = $4 WHERE id = $1",
+		o.ID, o.Name, o.Picture, o.Timezone)
+}
+
+func (r *organizationRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM organizations WHERE id = $1", id)
+}
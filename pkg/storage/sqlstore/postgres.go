@@ -0,0 +1,21 @@
+package sqlstore
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// postgresConflict reports whether err is a uniqueness or foreign key
+// violation raised by PostgreSQL.
+func postgresConflict(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+	switch pqErr.Code.Name() {
+	case "unique_violation", "foreign_key_violation":
+		return pqErr.Message, true
+	}
+	return "", false
+}
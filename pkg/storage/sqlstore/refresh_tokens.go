@@ -0,0 +1,56 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const refreshTokenColumns = "id, user_id, family, token_hash, expires_at, used, revoked"
+
+type refreshTokenRepo struct {
+	db conn
+}
+
+func scanRefreshToken(row scanner) (*storage.RefreshToken, error) {
+	var (
+		t       storage.RefreshToken
+		expires int64
+	)
+	if err := row.Scan(&t.ID, &t.UserID, &t.Family, &t.Hash, &expires, &t.Used, &t.Revoked); err != nil {
+		return nil, translate(err)
+	}
+	t.ExpiresAt = time.Unix(expires, 0)
+	return &t, nil
+}
+
+func (r *refreshTokenRepo) GetByHash(ctx context.Context, hash string) (*storage.RefreshToken, error) {
+	return scanRefreshToken(r.db.QueryRowContext(ctx,
+		"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1", hash))
+}
+
+func (r *refreshTokenRepo) Create(ctx context.Context, t *storage.RefreshToken) error {
+	t.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens ("+refreshTokenColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		t.ID, t.UserID, t.Family, t.Hash, t.ExpiresAt.Unix(), t.Used, t.Revoked)
+	return translate(err)
+}
+
+func (r *refreshTokenRepo) MarkUsed(ctx context.Context, id string) error {
+	return exec(ctx, r.db,
+		"UPDATE refresh_tokens SET used = TRUE WHERE id = $1 AND NOT used", id)
+}
+
+func (r *refreshTokenRepo) RevokeFamily(ctx context.Context, family string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked = TRUE WHERE family = $1", family)
+	return translate(err)
+}
+
+func (r *refreshTokenRepo) RevokeUser(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1", userID)
+	return translate(err)
+}
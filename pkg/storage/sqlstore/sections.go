@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+)
+
+const sectionColumns = "id, name, organization_id"
+
+type sectionRepo struct {
+	db conn
+}
+
+func scanSection(row scanner) (*model.Section, error) {
+	var s model.Section
+	if err := row.Scan(&s.ID, &s.Name, &s.OrganizationID); err != nil {
+		return nil, translate(err)
+	}
+	return &s, nil
+}
+
+func (r *sectionRepo) Get(ctx context.Context, id string) (*model.Section, error) {
+	return scanSection(r.db.QueryRowContext(ctx,
+		"SELECT "+sectionColumns+" FROM sections WHERE id = $1", id))
+}
+
+func (r *sectionRepo) GetMany(ctx context.Context, ids []string) ([]*model.Section, error) {
+	var w where
+	w.in("id", ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+sectionColumns+" FROM sections"+w.String(), w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var sections []*model.Section
+	for rows.Next() {
+		s, err := scanSection(rows)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, rows.Err()
+}
+
+// sectionKeys are the columns of storage.SectionKey.
+var sectionKeys = []keyColumn{{name: "name"}, {name: "id"}}
+
+func (r *sectionRepo) ListByOrganization(ctx context.Context, organizationID string, p *storage.Page) ([]*model.Section, error) {
+	var w where
+	w.add("organization_id = $%d", organizationID)
+	query, err := w.list(sectionColumns, "sections", sectionKeys, p)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var sections []*model.Section
+	for rows.Next() {
+		s, err := scanSection(rows)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, rows.Err()
+}
+
+func (r *sectionRepo) Create(ctx context.Context, s *model.Section) error {
+	s.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO sections ("+sectionColumns+") VALUES ($1, $2, $3)",
+		s.ID, s.Name, s.OrganizationID)
+	return translate(err)
+}
+
+func (r *sectionRepo) Update(ctx context.Context, s *model.Section) error {
+	return exec(ctx, r.db,
+		"UPDATE sections SET name = $2, organization_id = $3 WHERE id = $1",
+		s.ID, s.Name, s.OrganizationID)
+}
+
+func (r *sectionRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM sections WHERE id = $1", id)
+}
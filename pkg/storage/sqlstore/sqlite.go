@@ -0,0 +1,58 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlitePragmas are set on every connection. SQLite only enforces foreign
+// keys when asked to, and waits for locks instead of failing right away.
+var sqlitePragmas = []string{
+	"foreign_keys(1)",
+	"busy_timeout(5000)",
+	"journal_mode(WAL)",
+}
+
+// openSQLite opens the database file named by dsn, which may carry
+// additional modernc.org/sqlite query parameters.
+func openSQLite(dsn string) (*sql.DB, error) {
+	params := make([]string, len(sqlitePragmas))
+	for i, p := range sqlitePragmas {
+		params[i] = "_pragma=" + p
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+
+	db, err := sql.Open("sqlite", dsn+sep+strings.Join(params, "&"))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializes writers anyway; a single connection avoids
+	// SQLITE_BUSY between them and keeps :memory: databases shared.
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// sqliteConflict reports whether err is a uniqueness or foreign key
+// violation raised by SQLite. ON DELETE RESTRICT violations are reported
+// as trigger constraints.
+func sqliteConflict(err error) (string, bool) {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return "", false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE,
+		sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY,
+		sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY,
+		sqlite3.SQLITE_CONSTRAINT_TRIGGER:
+		return sqliteErr.Error(), true
+	}
+	return "", false
+}
@@ -0,0 +1,271 @@
+// Package sqlstore implements the storage repositories on top of
+// database/sql. PostgreSQL and SQLite are supported; both share the same
+// queries and migrations.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// DB is an open database holding the oaf-server tables.
+type DB struct {
+	*sql.DB
+}
+
+// Open connects to the database described by driver and dsn. The tables
+// are created and updated by the migrations, see MigrateUp.
+func Open(ctx context.Context, driver, dsn string) (*DB, error) {
+	var (
+		db  *sql.DB
+		err error
+	)
+	switch driver {
+	case "postgres":
+		db, err = sql.Open("postgres", dsn)
+	case "sqlite":
+		db, err = openSQLite(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db}, nil
+}
+
+// Store returns the repositories backed by db.
+func (db *DB) Store() *storage.Store {
+	return newStore(db.DB)
+}
+
+// conn is what the repositories run their queries on: the database, or a
+// transaction on it.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func newStore(c conn) *storage.Store {
+	return &storage.Store{
+		Users:         &userRepo{c},
+		Organizations: &organizationRepo{c},
+		Sections:      &sectionRepo{c},
+		Members:       &memberRepo{c},
+		Events:        &eventRepo{c},
+		Comments:      &commentRepo{c},
+		Attendees:     &attendeeRepo{c},
+		Invites:       &inviteRepo{c},
+		RefreshTokens: &refreshTokenRepo{c},
+		Transactor:    transactor{c},
+	}
+}
+
+type transactor struct {
+	c conn
+}
+
+// Atomic runs fn in a transaction. Within one, fn becomes part of it.
+func (t transactor) Atomic(ctx context.Context, fn func(*storage.Store) error) error {
+	return inTx(ctx, t.c, func(tx conn) error {
+		return fn(newStore(tx))
+	})
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func newID() string {
+	return uuid.New().String()
+}
+
+// translate maps driver errors onto the storage errors.
+func translate(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	if msg, ok := postgresConflict(err); ok {
+		return fmt.Errorf("%w: %s", storage.ErrConflict, msg)
+	}
+	if msg, ok := sqliteConflict(err); ok {
+		return fmt.Errorf("%w: %s", storage.ErrConflict, msg)
+	}
+	return err
+}
+
+// exec runs a statement that is expected to touch exactly one row.
+func exec(ctx context.Context, db conn, query string, args ...interface{}) error {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translate(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// where accumulates the conditions of a filtered list query.
+type where struct {
+	conds []string
+	args  []interface{}
+}
+
+// add appends cond, in which %d is replaced by the placeholder index of arg.
+func (w *where) add(cond string, arg interface{}) {
+	w.args = append(w.args, arg)
+	w.conds = append(w.conds, fmt.Sprintf(cond, len(w.args)))
+}
+
+// in appends a condition matching rows whose column holds any of values.
+func (w *where) in(column string, values []string) {
+	if len(values) == 0 {
+		w.conds = append(w.conds, "1 = 0")
+		return
+	}
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		w.args = append(w.args, v)
+		placeholders[i] = fmt.Sprintf("$%d", len(w.args))
+	}
+	w.conds = append(w.conds, column+" IN ("+strings.Join(placeholders, ", ")+")")
+}
+
+func (w *where) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
+// keyColumn is a column lists are sorted by. Nullable columns sort NULL
+// first, as keys do, and time columns are written by timeValue.
+type keyColumn struct {
+	name     string
+	nullable bool
+	time     bool
+}
+
+// list returns the query reading columns from the rows of table that
+// match w, sorted by the key columns keys and within p if it is not nil.
+// Pages taken from the end of a list are read backwards and put back in
+// order by an outer query.
+func (w *where) list(columns, table string, keys []keyColumn, p *storage.Page) (string, error) {
+	if p == nil {
+		return "SELECT " + columns + " FROM " + table + w.String() + orderBy(keys, false), nil
+	}
+	if p.After != nil {
+		if err := w.keyset(keys, p.After, ">"); err != nil {
+			return "", err
+		}
+	}
+	if p.Before != nil {
+		if err := w.keyset(keys, p.Before, "<"); err != nil {
+			return "", err
+		}
+	}
+	var limit string
+	if p.Limit > 0 {
+		limit = fmt.Sprintf(" LIMIT %d", p.Limit)
+	}
+	query := "SELECT " + columns + " FROM " + table + w.String() + orderBy(keys, p.Last) + limit
+	if p.Last {
+		query = "SELECT * FROM (" + query + ") AS page" + orderBy(keys, false)
+	}
+	return query, nil
+}
+
+// count returns the number of rows of table that match w.
+func (w *where) count(ctx context.Context, db conn, table string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+w.String(), w.args...).Scan(&n)
+	return n, translate(err)
+}
+
+func orderBy(keys []keyColumn, desc bool) string {
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = k.name
+		switch {
+		case desc && k.nullable:
+			cols[i] += " DESC NULLS LAST"
+		case desc:
+			cols[i] += " DESC"
+		case k.nullable:
+			cols[i] += " NULLS FIRST"
+		}
+	}
+	return " ORDER BY " + strings.Join(cols, ", ")
+}
+
+// keyset appends the condition selecting the rows whose keys compare to
+// key as op, which is < or >, does.
+func (w *where) keyset(keys []keyColumn, key storage.Key, op string) error {
+	if len(key) != len(keys) {
+		return storage.ErrInvalidKey
+	}
+	alts := make([]string, len(keys))
+	for i := range keys {
+		conds := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			cond, err := w.compare(keys[j], "=", key[j])
+			if err != nil {
+				return err
+			}
+			conds = append(conds, cond)
+		}
+		cond, err := w.compare(keys[i], op, key[i])
+		if err != nil {
+			return err
+		}
+		alts[i] = "(" + strings.Join(append(conds, cond), " AND ") + ")"
+	}
+	w.conds = append(w.conds, "("+strings.Join(alts, " OR ")+")")
+	return nil
+}
+
+// compare returns the condition comparing the column k to the value v of
+// a key, in which NULL is "" and less than anything else.
+func (w *where) compare(k keyColumn, op, v string) (string, error) {
+	if k.nullable && v == "" {
+		switch op {
+		case "=":
+			return k.name + " IS NULL", nil
+		case ">":
+			return k.name + " IS NOT NULL", nil
+		default:
+			return "1 = 0", nil
+		}
+	}
+	var arg interface{} = v
+	if k.time {
+		t, err := time.Parse(timeFormat, v)
+		if err != nil {
+			return "", storage.ErrInvalidKey
+		}
+		arg = timeValue(t)
+	}
+	w.args = append(w.args, arg)
+	cond := fmt.Sprintf("%s %s $%d", k.name, op, len(w.args))
+	if k.nullable && op == "<" {
+		cond = "(" + k.name + " IS NULL OR " + cond + ")"
+	}
+	return cond, nil
+}
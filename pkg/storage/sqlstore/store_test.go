@@ -0,0 +1,752 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+	"github.com/concertLabs/oaf-server/pkg/storage"
+	"github.com/concertLabs/oaf-server/pkg/storage/memory"
+	"github.com/concertLabs/oaf-server/pkg/storage/sqlstore"
+)
+
+// postgresEnv names the variable holding the DSN of a PostgreSQL
+// database to run the tests against as well. The database must be empty:
+// the tests migrate it up and all the way down again.
+const postgresEnv = "OAF_TEST_POSTGRES"
+
+// openDBs opens the SQL databases the tests run against: SQLite, and
+// PostgreSQL if postgresEnv is set.
+func openDBs(t *testing.T) map[string]*sqlstore.DB {
+	t.Helper()
+	ctx := context.Background()
+	dbs := map[string]*sqlstore.DB{}
+
+	db, err := sqlstore.Open(ctx, "sqlite", filepath.Join(t.TempDir(), "oaf.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	dbs["sqlite"] = db
+
+	if dsn := os.Getenv(postgresEnv); dsn != "" {
+		db, err := sqlstore.Open(ctx, "postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			for {
+				m, err := db.MigrateDown(ctx)
+				if err != nil {
+					t.Error(err)
+				}
+				if m == nil || err != nil {
+					break
+				}
+			}
+			db.Close()
+		})
+		dbs["postgres"] = db
+	}
+	return dbs
+}
+
+// TestStoreParity runs the same changes and lookups on the memory store
+// and the SQL stores and compares what they return.
+func TestStoreParity(t *testing.T) {
+	want := runScenario(t, memory.New())
+	for name, db := range openDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := db.MigrateUp(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got := runScenario(t, db.Store())
+			for i := 0; i < len(got) || i < len(want); i++ {
+				var g, w string
+				if i < len(got) {
+					g = got[i]
+				}
+				if i < len(want) {
+					w = want[i]
+				}
+				if g != w {
+					t.Errorf("step %d:\n got %s\nwant %s", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+// recorder logs the results of a scenario, naming entities by labels
+// rather than by the IDs the stores assign.
+type recorder struct {
+	t     *testing.T
+	s     *storage.Store
+	ctx   context.Context
+	names map[string]string
+	log   []string
+}
+
+func (r *recorder) name(id, label string) {
+	r.names[id] = label
+}
+
+func (r *recorder) id(id string) string {
+	if label, ok := r.names[id]; ok {
+		return label
+	}
+	return "?" + id
+}
+
+func (r *recorder) ids(ids []string) string {
+	labels := make([]string, len(ids))
+	for i, id := range ids {
+		labels[i] = r.id(id)
+	}
+	sort.Strings(labels)
+	return "[" + strings.Join(labels, " ") + "]"
+}
+
+func (r *recorder) optID(id *string) string {
+	if id == nil {
+		return "nil"
+	}
+	return r.id(*id)
+}
+
+func timeString(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func optTime(t *time.Time) string {
+	if t == nil {
+		return "nil"
+	}
+	return timeString(*t)
+}
+
+func optString(s *string) string {
+	if s == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%q", *s)
+}
+
+// printf logs a result.
+func (r *recorder) printf(format string, args ...interface{}) {
+	r.log = append(r.log, fmt.Sprintf(format, args...))
+}
+
+// must fails the test if err is not nil.
+func (r *recorder) must(step string, err error) {
+	r.t.Helper()
+	if err != nil {
+		r.t.Fatalf("%s: %v", step, err)
+	}
+}
+
+// errorf logs the code of err, which the scenario expects to be want.
+func (r *recorder) err(step string, err error, want error) {
+	r.t.Helper()
+	if !errors.Is(err, want) {
+		r.t.Errorf("%s = %v, want %v", step, err, want)
+	}
+	r.printf("%s: %s", step, apperr.CodeOf(err))
+}
+
+func (r *recorder) user(u *model.User) string {
+	return fmt.Sprintf("%s{%s %s %s super=%v token=%s}", r.id(u.ID), u.Username, u.Email, optString(u.Showname), u.Superuser, optString(u.CalendarTokenHash))
+}
+
+func (r *recorder) section(s *model.Section) string {
+	return fmt.Sprintf("%s{%s in %s}", r.id(s.ID), s.Name, r.id(s.OrganizationID))
+}
+
+func (r *recorder) sections(sections []*model.Section) string {
+	s := make([]string, len(sections))
+	for i, sec := range sections {
+		s[i] = r.section(sec)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) member(m *model.Member) string {
+	return fmt.Sprintf("%s{%s in %s right=%d}", r.id(m.ID), r.id(m.UserID), r.id(m.SectionID), m.Right)
+}
+
+func (r *recorder) members(members []*model.Member) string {
+	s := make([]string, len(members))
+	for i, m := range members {
+		s[i] = r.member(m)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) event(e *model.Event) string {
+	exdates := make([]string, len(e.ExDates))
+	for i, t := range e.ExDates {
+		exdates[i] = timeString(t)
+	}
+	return fmt.Sprintf("%s{%s desc=%s adress=%s %s-%s by %s org=%s sections=%s rrule=%s exdates=%v series=%s recurrence=%s uid=%s}",
+		r.id(e.ID), e.Name, optString(e.Description), optString(e.Adress), timeString(e.Start), optTime(e.End),
+		r.id(e.CreatorID), r.optID(e.OrganizationID), r.ids(e.SectionIDs), optString(e.Recurrence), exdates,
+		r.optID(e.SeriesID), optTime(e.RecurrenceID), optString(e.UID))
+}
+
+// events logs events in the order of their labels, as lists of events
+// come in no particular order.
+func (r *recorder) events(events []*model.Event) string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = r.event(e)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) comments(comments []*model.Comment) string {
+	s := make([]string, len(comments))
+	for i, c := range comments {
+		// CreatedAt is set by the stores, to the time of Create.
+		s[i] = fmt.Sprintf("%s{%q by %s on %s created=%v}", r.id(c.ID), c.Text, r.id(c.CreatorID), r.id(c.EventID), !c.CreatedAt.IsZero())
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) attendee(a *model.Attendee) string {
+	return fmt.Sprintf("%s{%s at %s@%s %s %s}", r.id(a.ID), r.id(a.UserID), r.id(a.EventID), optTime(a.Occurrence), a.Commitment, optString(a.Comment))
+}
+
+func (r *recorder) attendees(attendees []*model.Attendee) string {
+	s := make([]string, len(attendees))
+	for i, a := range attendees {
+		s[i] = r.attendee(a)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) invites(invites []*model.Invite) string {
+	s := make([]string, len(invites))
+	for i, inv := range invites {
+		s[i] = fmt.Sprintf("%s{%s %s to %s by %s right=%d expires=%s token=%s}", r.id(inv.ID), r.optID(inv.UserID), optString(inv.Email),
+			r.id(inv.SectionID), r.optID(inv.InvitedByID), inv.Right, optTime(inv.ExpiresAt), optString(inv.TokenHash))
+	}
+	return strings.Join(s, ", ")
+}
+
+func (r *recorder) token(t *storage.RefreshToken) string {
+	return fmt.Sprintf("%s{%s family=%s %s expires=%s used=%v revoked=%v}", r.id(t.ID), r.id(t.UserID), t.Family, t.Hash, timeString(t.ExpiresAt), t.Used, t.Revoked)
+}
+
+// selected returns the keys of a list that p selects, given all keys of
+// the list in order.
+func selected(all []storage.Key, p *storage.Page) []storage.Key {
+	var keys []storage.Key
+	for _, k := range all {
+		if p != nil && (p.After != nil && !p.After.Less(k) || p.Before != nil && !k.Less(p.Before)) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if p != nil && p.Limit > 0 && len(keys) > p.Limit {
+		if p.Last {
+			keys = keys[len(keys)-p.Limit:]
+		} else {
+			keys = keys[:p.Limit]
+		}
+	}
+	return keys
+}
+
+// checkList fails the test unless keys, those of a list read with p, are
+// what p selects from all, the keys of the whole list, and all is in
+// order. As keys end in IDs, which differ between stores, the order of
+// lists and the items pages hold are checked here rather than compared
+// between stores.
+func (r *recorder) checkList(step string, all, keys []storage.Key, p *storage.Page) {
+	r.t.Helper()
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].Less(all[j]) }) {
+		r.t.Errorf("%s: list is not in the order of its keys: %q", step, all)
+	}
+	if want := selected(all, p); fmt.Sprint(keys) != fmt.Sprint(want) {
+		r.t.Errorf("%s: page %+v holds %q, want %q", step, p, keys, want)
+	}
+}
+
+// labels returns the sorted labels of ids.
+func (r *recorder) labels(ids []string) []string {
+	labels := make([]string, len(ids))
+	for i, id := range ids {
+		labels[i] = r.id(id)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func str(s string) *string                            { return &s }
+func at(t time.Time) *time.Time                       { return &t }
+func right(n int) *int                                { return &n }
+func commitment(c model.Commitment) *model.Commitment { return &c }
+
+// runScenario changes and reads s and returns the log of the results.
+func runScenario(t *testing.T, s *storage.Store) []string {
+	r := &recorder{t: t, s: s, ctx: context.Background(), names: map[string]string{}}
+	ctx := r.ctx
+	base := time.Date(2030, 1, 7, 18, 0, 0, 0, time.UTC)
+
+	// Users
+	users := map[string]*model.User{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u := &model.User{Username: name, Password: "hash", Email: name + "@example.org"}
+		r.must("create user", s.Users.Create(ctx, u))
+		r.name(u.ID, name)
+		users[name] = u
+	}
+	alice, bob, carol := users["alice"], users["bob"], users["carol"]
+	r.err("create user with a taken username", s.Users.Create(ctx, &model.User{Username: "alice", Email: "other@example.org"}), storage.ErrConflict)
+	_, err := s.Users.Get(ctx, "unknown")
+	r.err("get unknown user", err, storage.ErrNotFound)
+	_, err = s.Users.GetByUsername(ctx, "dave")
+	r.err("get unknown username", err, storage.ErrNotFound)
+	r.err("update unknown user", s.Users.Update(ctx, &model.User{ID: "unknown", Username: "x"}), storage.ErrNotFound)
+	r.err("delete unknown user", s.Users.Delete(ctx, "unknown"), storage.ErrNotFound)
+
+	bob.Email, bob.Showname, bob.Superuser, bob.CalendarTokenHash = "robert@example.org", str("Bob"), true, str("calendar hash")
+	r.must("update user", s.Users.Update(ctx, bob))
+	u, err := s.Users.GetByUsername(ctx, "bob")
+	r.must("get by username", err)
+	r.printf("by username: %s", r.user(u))
+	u, err = s.Users.GetByCalendarTokenHash(ctx, "calendar hash")
+	r.must("get by calendar token", err)
+	r.printf("by calendar token: %s", r.user(u))
+	_, err = s.Users.GetByCalendarTokenHash(ctx, "other hash")
+	r.err("get by unknown calendar token", err, storage.ErrNotFound)
+	many, err := s.Users.GetMany(ctx, []string{carol.ID, "unknown", alice.ID, alice.ID})
+	r.must("get many users", err)
+	var got []string
+	for _, u := range many {
+		got = append(got, r.user(u))
+	}
+	sort.Strings(got)
+	r.printf("many users: %v", got)
+
+	// Organizations and sections
+	orchestra := &model.Organization{Name: "Orchestra", Timezone: "Europe/Berlin"}
+	r.must("create organization", s.Organizations.Create(ctx, orchestra))
+	r.name(orchestra.ID, "orchestra")
+	choir := &model.Organization{Name: "Choir", Timezone: "UTC", Picture: str("choir.png")}
+	r.must("create organization", s.Organizations.Create(ctx, choir))
+	r.name(choir.ID, "choir")
+	orchestra.Name, orchestra.Picture = "Symphony Orchestra", str("orchestra.png")
+	r.must("update organization", s.Organizations.Update(ctx, orchestra))
+	orgs, err := s.Organizations.GetMany(ctx, []string{orchestra.ID, choir.ID})
+	r.must("get many organizations", err)
+	var orgNames []string
+	for _, o := range orgs {
+		orgNames = append(orgNames, fmt.Sprintf("%s{%s %s %s}", r.id(o.ID), o.Name, optString(o.Picture), o.Timezone))
+	}
+	sort.Strings(orgNames)
+	r.printf("organizations: %v", orgNames)
+
+	sections := map[string]*model.Section{}
+	for _, name := range []string{"Winds", "Strings", "Brass", "Percussion", "Keys"} {
+		sec := &model.Section{Name: name, OrganizationID: orchestra.ID}
+		r.must("create section", s.Sections.Create(ctx, sec))
+		r.name(sec.ID, strings.ToLower(name))
+		sections[name] = sec
+	}
+	sopranos := &model.Section{Name: "Sopranos", OrganizationID: choir.ID}
+	r.must("create section", s.Sections.Create(ctx, sopranos))
+	r.name(sopranos.ID, "sopranos")
+	strs, winds, brass, keys := sections["Strings"], sections["Winds"], sections["Brass"], sections["Keys"]
+	r.err("create section of unknown organization", s.Sections.Create(ctx, &model.Section{Name: "X", OrganizationID: "unknown"}), storage.ErrConflict)
+	keys.Name = "Piano"
+	r.must("update section", s.Sections.Update(ctx, keys))
+
+	percussionKey := storage.SectionKey(sections["Percussion"])
+	r.must("delete section", s.Sections.Delete(ctx, sections["Percussion"].ID))
+	all, err := s.Sections.ListByOrganization(ctx, orchestra.ID, nil)
+	r.must("list sections", err)
+	var allKeys []storage.Key
+	for _, sec := range all {
+		allKeys = append(allKeys, storage.SectionKey(sec))
+	}
+	for i, p := range []*storage.Page{
+		nil,
+		{},
+		{Limit: 2},
+		{Limit: 2, Last: true},
+		{After: storage.SectionKey(brass)},
+		{After: storage.SectionKey(brass), Limit: 1},
+		{Before: storage.SectionKey(winds), Limit: 2, Last: true},
+		{After: storage.SectionKey(brass), Before: storage.SectionKey(winds)},
+		{After: percussionKey},
+		{Before: percussionKey},
+		{After: storage.Key{"", ""}, Limit: 1},
+		{After: storage.SectionKey(winds)},
+	} {
+		list, err := s.Sections.ListByOrganization(ctx, orchestra.ID, p)
+		r.must("list sections", err)
+		var keys []storage.Key
+		for _, sec := range list {
+			keys = append(keys, storage.SectionKey(sec))
+		}
+		r.checkList(fmt.Sprintf("sections %d", i), allKeys, keys, p)
+		r.printf("sections %d: %s", i, r.sections(list))
+	}
+	_, err = s.Sections.ListByOrganization(ctx, orchestra.ID, &storage.Page{After: storage.Key{"Brass"}})
+	r.err("list sections after a key of another list", err, storage.ErrInvalidKey)
+
+	// Members
+	for _, m := range []struct {
+		user    *model.User
+		section *model.Section
+		right   int
+	}{
+		{alice, strs, 1}, {bob, strs, 3}, {alice, winds, 0}, {carol, brass, 1}, {bob, sopranos, 7},
+	} {
+		mem := &model.Member{UserID: m.user.ID, SectionID: m.section.ID, Right: m.right}
+		r.must("create member", s.Members.Create(ctx, mem))
+		r.name(mem.ID, r.id(m.user.ID)+"@"+r.id(m.section.ID))
+	}
+	r.err("create member twice", s.Members.Create(ctx, &model.Member{UserID: alice.ID, SectionID: strs.ID}), storage.ErrConflict)
+	r.err("create member of unknown user", s.Members.Create(ctx, &model.Member{UserID: "unknown", SectionID: strs.ID}), storage.ErrConflict)
+	r.err("create member of unknown section", s.Members.Create(ctx, &model.Member{UserID: alice.ID, SectionID: "unknown"}), storage.ErrConflict)
+	m, err := s.Members.GetBySectionUser(ctx, strs.ID, bob.ID)
+	r.must("get member by section and user", err)
+	m.Right = 5
+	r.must("update member", s.Members.Update(ctx, m))
+	_, err = s.Members.GetBySectionUser(ctx, winds.ID, bob.ID)
+	r.err("get member by section and other user", err, storage.ErrNotFound)
+	for i, f := range []storage.MemberFilter{
+		{},
+		{SectionID: &strs.ID},
+		{SectionIDs: []string{strs.ID, brass.ID}},
+		{SectionIDs: []string{}},
+		{UserID: &alice.ID},
+		{Right: right(1)},
+		{SectionID: &strs.ID, Page: &storage.Page{Limit: 1}},
+		{SectionID: &strs.ID, Page: &storage.Page{Limit: 1, Last: true}},
+		{UserID: &alice.ID, Page: &storage.Page{After: storage.MemberKey(m)}},
+	} {
+		list, err := s.Members.List(ctx, f)
+		r.must("list members", err)
+		page := f.Page
+		f.Page = nil
+		all, err := s.Members.List(ctx, f)
+		r.must("list members", err)
+		var allKeys, keys []storage.Key
+		for _, m := range all {
+			allKeys = append(allKeys, storage.MemberKey(m))
+		}
+		var ids []string
+		for _, m := range list {
+			keys = append(keys, storage.MemberKey(m))
+			ids = append(ids, m.ID)
+		}
+		r.checkList(fmt.Sprintf("members %d", i), allKeys, keys, page)
+		if page == nil {
+			r.printf("members %d: %s", i, r.labels(ids))
+		} else {
+			r.printf("members %d: a page of %d", i, len(all))
+		}
+	}
+
+	// Events
+	e1 := &model.Event{
+		Name: "Tutti", Description: str("Bring stands"), Start: base, End: at(base.Add(3 * time.Hour)),
+		CreatorID: alice.ID, OrganizationID: &orchestra.ID, SectionIDs: []string{strs.ID, winds.ID}, UID: str("tutti@oaf"),
+	}
+	r.must("create event", s.Events.Create(ctx, e1))
+	r.name(e1.ID, "tutti")
+	series := &model.Event{
+		Name: "Sectional", Start: base.Add(24 * time.Hour), End: at(base.Add(26 * time.Hour)),
+		CreatorID: bob.ID, OrganizationID: &orchestra.ID, SectionIDs: []string{strs.ID},
+		Recurrence: str("FREQ=WEEKLY"), ExDates: []time.Time{base.Add(15 * 24 * time.Hour), base.Add(8 * 24 * time.Hour)},
+	}
+	r.must("create event", s.Events.Create(ctx, series))
+	r.name(series.ID, "sectional")
+	moved := &model.Event{
+		Name: "Sectional, later", Start: base.Add(8*24*time.Hour + time.Hour), End: at(base.Add(8*24*time.Hour + 3*time.Hour)),
+		CreatorID: bob.ID, OrganizationID: &orchestra.ID, SeriesID: &series.ID, RecurrenceID: at(base.Add(8 * 24 * time.Hour)),
+	}
+	r.must("create event", s.Events.Create(ctx, moved))
+	r.name(moved.ID, "moved")
+	private := &model.Event{Name: "Lesson", Start: base.Add(48 * time.Hour), CreatorID: carol.ID, Adress: str("Room 1")}
+	r.must("create event", s.Events.Create(ctx, private))
+	r.name(private.ID, "lesson")
+	r.err("create event of unknown creator", s.Events.Create(ctx, &model.Event{Name: "X", Start: base, CreatorID: "unknown"}), storage.ErrConflict)
+	r.err("create event for unknown section", s.Events.Create(ctx, &model.Event{Name: "X", Start: base, CreatorID: alice.ID, OrganizationID: &orchestra.ID, SectionIDs: []string{"unknown"}}), storage.ErrConflict)
+
+	e1.Name, e1.SectionIDs, e1.Description = "Tutti rehearsal", []string{brass.ID}, nil
+	r.must("update event", s.Events.Update(ctx, e1))
+	e, err := s.Events.Get(ctx, e1.ID)
+	r.must("get event", err)
+	r.printf("event: %s", r.event(e))
+	e, err = s.Events.Get(ctx, series.ID)
+	r.must("get event", err)
+	r.printf("event: %s", r.event(e))
+	evs, err := s.Events.GetMany(ctx, []string{moved.ID, private.ID, "unknown"})
+	r.must("get many events", err)
+	r.printf("many events: %s", r.events(evs))
+	recurring, single := true, false
+	for i, f := range []storage.EventFilter{
+		{},
+		{OrganizationID: &orchestra.ID},
+		{OrganizationID: &choir.ID},
+		{SeriesID: &series.ID},
+		{SeriesIDs: []string{series.ID, e1.ID}},
+		{SeriesIDs: []string{}},
+		{UID: str("tutti@oaf")},
+		{Start: at(base.Add(time.Hour))},
+		{End: at(base.Add(24 * time.Hour))},
+		{Start: at(base.Add(30 * time.Hour)), End: at(base.Add(72 * time.Hour))},
+		{Recurring: &recurring},
+		{Recurring: &single, OrganizationID: &orchestra.ID},
+		{Page: &storage.Page{Limit: 2}},
+		{OrganizationID: &orchestra.ID, Page: &storage.Page{Limit: 2, Last: true}},
+		{Page: &storage.Page{After: storage.Key{storage.KeyTime(base), ""}, Before: storage.Key{storage.KeyTime(base.Add(48 * time.Hour)), ""}}},
+	} {
+		list, err := s.Events.List(ctx, f)
+		r.must("list events", err)
+		page := f.Page
+		f.Page = nil
+		all, err := s.Events.List(ctx, f)
+		r.must("list events", err)
+		var allKeys, keys []storage.Key
+		for _, e := range all {
+			allKeys = append(allKeys, storage.EventKey(e))
+		}
+		for _, e := range list {
+			keys = append(keys, storage.EventKey(e))
+		}
+		r.checkList(fmt.Sprintf("events %d", i), allKeys, keys, page)
+		n, err := s.Events.Count(ctx, f)
+		r.must("count events", err)
+		r.printf("events %d: %s, %d in all", i, r.events(list), n)
+	}
+
+	// Comments
+	for i, text := range []string{"first", "second", "third"} {
+		c := &model.Comment{Text: text, CreatorID: alice.ID, EventID: e1.ID, CreatedAt: base.Add(time.Duration(2-i) * time.Minute)}
+		r.must("create comment", s.Comments.Create(ctx, c))
+		r.name(c.ID, text)
+		if text == "second" {
+			c.Text = "second, edited"
+			r.must("update comment", s.Comments.Update(ctx, c))
+		}
+		if text == "third" {
+			r.must("delete comment", s.Comments.Delete(ctx, c.ID))
+		}
+	}
+	r.err("create comment on unknown event", s.Comments.Create(ctx, &model.Comment{Text: "x", CreatorID: alice.ID, EventID: "unknown", CreatedAt: base}), storage.ErrConflict)
+	comments, err := s.Comments.ListByEvent(ctx, e1.ID, nil)
+	r.must("list comments", err)
+	var commentKeys []storage.Key
+	for _, c := range comments {
+		commentKeys = append(commentKeys, storage.CommentKey(c))
+	}
+	r.checkList("comments", commentKeys, commentKeys, nil)
+	for i, p := range []*storage.Page{
+		{Limit: 1},
+		{Limit: 1, Last: true},
+		{After: commentKeys[0]},
+	} {
+		page, err := s.Comments.ListByEvent(ctx, e1.ID, p)
+		r.must("list comments", err)
+		var keys []storage.Key
+		for _, c := range page {
+			keys = append(keys, storage.CommentKey(c))
+		}
+		r.checkList(fmt.Sprintf("comments %d", i), commentKeys, keys, p)
+	}
+	n, err := s.Comments.CountByEvent(ctx, e1.ID)
+	r.must("count comments", err)
+	r.printf("%d comments", n)
+	sort.Slice(comments, func(i, j int) bool { return r.id(comments[i].ID) < r.id(comments[j].ID) })
+	r.printf("comments: %s", r.comments(comments))
+
+	// Attendees
+	for _, a := range []*model.Attendee{
+		{UserID: alice.ID, EventID: e1.ID, Commitment: model.CommitmentYes},
+		{UserID: bob.ID, EventID: e1.ID, Commitment: model.CommitmentNo, Comment: str("ill")},
+		{UserID: alice.ID, EventID: series.ID, Occurrence: at(base.Add(24 * time.Hour)), Commitment: model.CommitmentMaybe},
+		{UserID: alice.ID, EventID: series.ID, Occurrence: at(base.Add(22 * 24 * time.Hour)), Commitment: model.CommitmentNo},
+		{UserID: bob.ID, EventID: moved.ID, Commitment: model.CommitmentYes},
+	} {
+		r.must("create attendee", s.Attendees.Create(ctx, a))
+		r.name(a.ID, fmt.Sprintf("%s@%s/%s", r.id(a.UserID), r.id(a.EventID), optTime(a.Occurrence)))
+	}
+	r.err("create attendee twice", s.Attendees.Create(ctx, &model.Attendee{UserID: alice.ID, EventID: e1.ID, Commitment: model.CommitmentNo}), storage.ErrConflict)
+	r.err("create attendee of unknown event", s.Attendees.Create(ctx, &model.Attendee{UserID: alice.ID, EventID: "unknown", Commitment: model.CommitmentNo}), storage.ErrConflict)
+	a, err := s.Attendees.GetByEventUser(ctx, series.ID, alice.ID, at(base.Add(24*time.Hour)))
+	r.must("get attendee by event and user", err)
+	r.printf("attendee: %s", r.attendee(a))
+	_, err = s.Attendees.GetByEventUser(ctx, series.ID, alice.ID, nil)
+	r.err("get attendee of the whole series", err, storage.ErrNotFound)
+	a.Commitment, a.Comment, a.Occurrence = model.CommitmentYes, str("after all"), at(base.Add(29*24*time.Hour))
+	r.must("update attendee", s.Attendees.Update(ctx, a))
+	for i, f := range []storage.AttendeeFilter{
+		{EventID: &e1.ID},
+		{EventIDs: []string{e1.ID, moved.ID}},
+		{EventID: &series.ID, Occurrence: at(base.Add(29 * 24 * time.Hour))},
+		{UserID: &alice.ID, EventID: &series.ID},
+		{EventID: &e1.ID, Commitment: commitment(model.CommitmentNo)},
+		{EventID: &series.ID, Page: &storage.Page{Limit: 1}},
+		{EventID: &series.ID, Page: &storage.Page{Limit: 1, Last: true}},
+		{EventID: &e1.ID, Page: &storage.Page{After: storage.Key{e1.ID, "", ""}}},
+	} {
+		list, err := s.Attendees.List(ctx, f)
+		r.must("list attendees", err)
+		page := f.Page
+		f.Page = nil
+		all, err := s.Attendees.List(ctx, f)
+		r.must("list attendees", err)
+		var allKeys, keys []storage.Key
+		for _, a := range all {
+			allKeys = append(allKeys, storage.AttendeeKey(a))
+		}
+		for _, a := range list {
+			keys = append(keys, storage.AttendeeKey(a))
+		}
+		r.checkList(fmt.Sprintf("attendees %d", i), allKeys, keys, page)
+		n, err := s.Attendees.Count(ctx, f)
+		r.must("count attendees", err)
+		sort.Slice(list, func(i, j int) bool { return r.id(list[i].ID) < r.id(list[j].ID) })
+		r.printf("attendees %d: %s, %d in all", i, r.attendees(list), n)
+	}
+
+	// Invites
+	expires := base.Add(7 * 24 * time.Hour)
+	for _, inv := range []*model.Invite{
+		{Email: str("dave@example.org"), SectionID: strs.ID, InvitedByID: &bob.ID, Right: 1, ExpiresAt: &expires, TokenHash: str("invite hash")},
+		{UserID: &carol.ID, SectionID: strs.ID, InvitedByID: &bob.ID, Right: 0},
+		{UserID: &carol.ID, SectionID: winds.ID, Right: 3},
+	} {
+		r.must("create invite", s.Invites.Create(ctx, inv))
+		r.name(inv.ID, "invite"+fmt.Sprint(len(r.names)))
+	}
+	inv, err := s.Invites.GetByTokenHash(ctx, "invite hash")
+	r.must("get invite by token", err)
+	r.printf("invite by token: %s", r.invites([]*model.Invite{inv}))
+	for i, f := range []storage.InviteFilter{
+		{SectionID: &strs.ID},
+		{UserID: &carol.ID},
+		{SectionID: &strs.ID, UserID: &carol.ID},
+	} {
+		list, err := s.Invites.List(ctx, f)
+		r.must("list invites", err)
+		var keys []storage.Key
+		for _, inv := range list {
+			keys = append(keys, storage.InviteKey(inv))
+		}
+		r.checkList(fmt.Sprintf("invites %d", i), keys, keys, nil)
+		sort.Slice(list, func(i, j int) bool { return r.id(list[i].ID) < r.id(list[j].ID) })
+		r.printf("invites %d: %s", i, r.invites(list))
+	}
+	r.must("delete invite", s.Invites.Delete(ctx, inv.ID))
+	_, err = s.Invites.Get(ctx, inv.ID)
+	r.err("get deleted invite", err, storage.ErrNotFound)
+
+	// Refresh tokens
+	tokens := map[string]*storage.RefreshToken{}
+	for _, tt := range []struct{ name, user, family string }{
+		{"t1", alice.ID, "f1"}, {"t2", alice.ID, "f1"}, {"t3", alice.ID, "f2"}, {"t4", bob.ID, "f3"},
+	} {
+		tok := &storage.RefreshToken{UserID: tt.user, Family: tt.family, Hash: tt.name + " hash", ExpiresAt: expires}
+		r.must("create refresh token", s.RefreshTokens.Create(ctx, tok))
+		r.name(tok.ID, tt.name)
+		tokens[tt.name] = tok
+	}
+	r.must("mark used", s.RefreshTokens.MarkUsed(ctx, tokens["t1"].ID))
+	r.err("mark used twice", s.RefreshTokens.MarkUsed(ctx, tokens["t1"].ID), storage.ErrNotFound)
+	r.must("revoke family", s.RefreshTokens.RevokeFamily(ctx, "f1"))
+	r.must("revoke user", s.RefreshTokens.RevokeUser(ctx, bob.ID))
+	for _, name := range []string{"t1", "t2", "t3", "t4"} {
+		tok, err := s.RefreshTokens.GetByHash(ctx, name+" hash")
+		r.must("get refresh token", err)
+		r.printf("refresh token: %s", r.token(tok))
+	}
+
+	// Transactions
+	failed := errors.New("failed")
+	err = s.Atomic(ctx, func(tx *storage.Store) error {
+		r.must("create user in a transaction", tx.Users.Create(ctx, &model.User{Username: "dave", Email: "dave@example.org"}))
+		r.must("delete section in a transaction", tx.Sections.Delete(ctx, winds.ID))
+		return failed
+	})
+	r.err("failed transaction", err, failed)
+	_, err = s.Users.GetByUsername(ctx, "dave")
+	r.err("get user created by a failed transaction", err, storage.ErrNotFound)
+	_, err = s.Sections.Get(ctx, winds.ID)
+	r.must("get section deleted by a failed transaction", err)
+	err = s.Atomic(ctx, func(tx *storage.Store) error {
+		return tx.Atomic(ctx, func(tx *storage.Store) error {
+			return tx.Users.Create(ctx, &model.User{Username: "erin", Email: "erin@example.org"})
+		})
+	})
+	r.must("nested transaction", err)
+	u, err = s.Users.GetByUsername(ctx, "erin")
+	r.must("get user created by a nested transaction", err)
+	r.name(u.ID, "erin")
+	err = s.Atomic(ctx, func(tx *storage.Store) error {
+		r.must("create user in an outer transaction", tx.Users.Create(ctx, &model.User{Username: "frank", Email: "frank@example.org"}))
+		r.must("inner transaction", tx.Atomic(ctx, func(tx *storage.Store) error {
+			return tx.Users.Create(ctx, &model.User{Username: "gina", Email: "gina@example.org"})
+		}))
+		return failed
+	})
+	r.err("failed outer transaction", err, failed)
+	for _, name := range []string{"frank", "gina"} {
+		_, err = s.Users.GetByUsername(ctx, name)
+		r.err("get user created in a failed outer transaction", err, storage.ErrNotFound)
+	}
+
+	// Deletes
+	r.must("delete section", s.Sections.Delete(ctx, strs.ID))
+	members, err := s.Members.List(ctx, storage.MemberFilter{UserID: &bob.ID})
+	r.must("list members", err)
+	r.printf("bob's members after deleting strings: %s", r.members(members))
+	invites, err := s.Invites.List(ctx, storage.InviteFilter{UserID: &carol.ID})
+	r.must("list invites", err)
+	r.printf("carol's invites after deleting strings: %s", r.invites(invites))
+	e, err = s.Events.Get(ctx, series.ID)
+	r.must("get event", err)
+	r.printf("event for a deleted section: %s", r.event(e))
+
+	r.must("delete event", s.Events.Delete(ctx, series.ID))
+	_, err = s.Events.Get(ctx, moved.ID)
+	r.err("get event replacing an occurrence of a deleted event", err, storage.ErrNotFound)
+	attendees, err := s.Attendees.List(ctx, storage.AttendeeFilter{UserID: &alice.ID})
+	r.must("list attendees", err)
+	r.printf("alice's attendees after deleting the series: %s", r.attendees(attendees))
+	r.must("delete comment", s.Comments.Delete(ctx, comments[0].ID))
+	r.err("delete deleted comment", s.Comments.Delete(ctx, comments[0].ID), storage.ErrNotFound)
+
+	r.must("delete organization", s.Organizations.Delete(ctx, orchestra.ID))
+	list, err := s.Sections.ListByOrganization(ctx, orchestra.ID, nil)
+	r.must("list sections", err)
+	r.printf("sections after deleting the organization: %s", r.sections(list))
+	evs, err = s.Events.List(ctx, storage.EventFilter{})
+	r.must("list events", err)
+	r.printf("events after deleting the organization: %s", r.events(evs))
+	_, err = s.Comments.Get(ctx, comments[1].ID)
+	r.err("get comment of a deleted event", err, storage.ErrNotFound)
+
+	r.must("delete user", s.Users.Delete(ctx, bob.ID))
+	members, err = s.Members.List(ctx, storage.MemberFilter{SectionID: &sopranos.ID})
+	r.must("list members", err)
+	r.printf("sopranos after deleting bob: %s", r.members(members))
+	_, err = s.RefreshTokens.GetByHash(ctx, "t4 hash")
+	r.err("get refresh token of a deleted user", err, storage.ErrNotFound)
+
+	return r.log
+}
@@ -0,0 +1,124 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Times are written as UTC strings of fixed width, which PostgreSQL parses
+// into its TIMESTAMPTZ columns and which SQLite, storing them as text,
+// compares in chronological order.
+const timeFormat = "2006-01-02T15:04:05Z"
+
+// timeValue is the value a time is written as.
+type timeValue time.Time
+
+func (t timeValue) Value() (driver.Value, error) {
+	return time.Time(t).UTC().Format(timeFormat), nil
+}
+
+// nullTimeValue is timeValue for nullable columns.
+func nullTimeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return timeValue(*t)
+}
+
+// sqliteTimeFormat is the layout of CURRENT_TIMESTAMP in SQLite, which
+// column defaults may have written.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// timeScanner reads a time written by timeValue into t. PostgreSQL returns
+// time.Time, SQLite the stored text.
+type timeScanner struct {
+	t *time.Time
+}
+
+func (s timeScanner) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case time.Time:
+		*s.t = v
+	case string:
+		*s.t, err = parseTime(v)
+	case []byte:
+		*s.t, err = parseTime(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	*s.t = s.t.UTC()
+	return err
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(sqliteTimeFormat, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// nullTimeScanner is timeScanner for nullable columns.
+type nullTimeScanner struct {
+	t **time.Time
+}
+
+func (s nullTimeScanner) Scan(src interface{}) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (timeScanner{&t}).Scan(src); err != nil {
+		return err
+	}
+	*s.t = &t
+	return nil
+}
+
+// timeListFormat is the layout of the times in lists like EXDATE.
+const timeListFormat = "20060102T150405Z"
+
+// timeListValue writes times as a comma separated list, or NULL if there
+// are none.
+func timeListValue(ts []time.Time) interface{} {
+	if len(ts) == 0 {
+		return nil
+	}
+	list := make([]string, len(ts))
+	for i, t := range ts {
+		list[i] = t.UTC().Format(timeListFormat)
+	}
+	return strings.Join(list, ",")
+}
+
+// timeListScanner reads a list written by timeListValue into ts.
+type timeListScanner struct {
+	ts *[]time.Time
+}
+
+func (s timeListScanner) Scan(src interface{}) error {
+	var list string
+	switch v := src.(type) {
+	case nil:
+		*s.ts = nil
+		return nil
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a list of times", src)
+	}
+	*s.ts = nil
+	for _, v := range strings.Split(list, ",") {
+		t, err := time.Parse(timeListFormat, v)
+		if err != nil {
+			return err
+		}
+		*s.ts = append(*s.ts, t)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+const userColumns = "id, username, password, email, showname, superuser, calendar_token_hash"
+
+type userRepo struct {
+	db conn
+}
+
+func scanUser(row scanner) (*model.User, error) {
+	var u model.User
+	if err := row.Scan(&u.ID, &u.Username, &u.Password, &u.Email, &u.Showname, &u.Superuser, &u.CalendarTokenHash); err != nil {
+		return nil, translate(err)
+	}
+	return &u, nil
+}
+
+func (r *userRepo) Get(ctx context.Context, id string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = $1", id))
+}
+
+func (r *userRepo) GetMany(ctx context.Context, ids []string) ([]*model.User, error) {
+	var w where
+	w.in("id", ids)
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users"+w.String(), w.args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE username = $1", username))
+}
+
+func (r *userRepo) GetByCalendarTokenHash(ctx context.Context, hash string) (*model.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE calendar_token_hash = $1", hash))
+}
+
+func (r *userRepo) Create(ctx context.Context, u *model.User) error {
+	u.ID = newID()
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO users ("+userColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		u.ID, u.Username, u.Password, u.Email, u.Showname, u.Superuser, u.CalendarTokenHash)
+	return translate(err)
+}
+
+func (r *userRepo) Update(ctx context.Context, u *model.User) error {
+	return exec(ctx, r.db,
+		"UPDATE users SET username = $2, password = $3, email = $4, showname = $5, superuser = $6, calendar_token_hash = $7 WHERE id = $1",
+		u.ID, u.Username, u.Password, u.Email, u.Showname, u.Superuser, u.CalendarTokenHash)
+}
+
+func (r *userRepo) Delete(ctx context.Context, id string) error {
+	return exec(ctx, r.db, "DELETE FROM users WHERE id = $1", id)
+}
@@ -0,0 +1,282 @@
+// Package storage defines the repositories the resolvers persist entities
+// through. Implementations live in the subpackages.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/concertLabs/oaf-server/pkg/apperr"
+	"github.com/concertLabs/oaf-server/pkg/graph/model"
+)
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist.
+	ErrNotFound = apperr.New(apperr.NotFound, "not found")
+	// ErrConflict is returned when a write would violate a uniqueness or
+	// foreign key constraint.
+	ErrConflict = apperr.New(apperr.Conflict, "conflict")
+	// ErrInvalidKey is returned for pages whose keys do not fit the list.
+	// Keys come from the cursors of clients.
+	ErrInvalidKey = apperr.New(apperr.Validation, "invalid cursor")
+)
+
+// Store bundles one repository per entity.
+type Store struct {
+	Users         UserRepository
+	Organizations OrganizationRepository
+	Sections      SectionRepository
+	Members       MemberRepository
+	Events        EventRepository
+	Comments      CommentRepository
+	Attendees     AttendeeRepository
+	Invites       InviteRepository
+	RefreshTokens RefreshTokenRepository
+	Transactor
+}
+
+// Transactor makes changes to several repositories at once.
+type Transactor interface {
+	// Atomic calls fn with a store whose changes take effect only if fn
+	// returns nil, and then all at once. Until fn returns, it must use
+	// that store only: other stores may block meanwhile.
+	Atomic(ctx context.Context, fn func(s *Store) error) error
+}
+
+// Page selects a part of a list, for paging through it the way cursor
+// connections do. Lists are ordered by the keys of their items, which the
+// key functions below return: the values of the columns the list is
+// sorted by, with times formatted by KeyTime, NULL as "" sorting first,
+// and ending in columns that make the keys unique. Items are located by
+// comparing keys, so the items whose keys a Page holds need not exist
+// anymore.
+type Page struct {
+	// After and Before, if set, leave out the items with keys up to and
+	// including After, and from Before on.
+	After, Before Key
+	// Limit, if positive, is the most items returned.
+	Limit int
+	// Last makes Limit take the items from the end of the selection
+	// rather than from its start. They are returned in list order all the
+	// same.
+	Last bool
+}
+
+// A Key is the position of an item in a list, see Page.
+type Key []string
+
+// Less compares keys column by column, like ORDER BY does.
+func (k Key) Less(o Key) bool {
+	for i := 0; i < len(k) && i < len(o); i++ {
+		if k[i] != o[i] {
+			return k[i] < o[i]
+		}
+	}
+	return len(k) < len(o)
+}
+
+// KeyTime formats t as a column of a key, the zero time being NULL.
+func KeyTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// Sections are listed by name.
+func SectionKey(s *model.Section) Key { return Key{s.Name, s.ID} }
+
+// Members are listed by section and user.
+func MemberKey(m *model.Member) Key { return Key{m.SectionID, m.UserID} }
+
+// Events are listed by start.
+func EventKey(e *model.Event) Key { return Key{KeyTime(e.Start), e.ID} }
+
+// Comments are listed by creation.
+func CommentKey(c *model.Comment) Key { return Key{KeyTime(c.CreatedAt), c.ID} }
+
+// Attendees are listed by event, occurrence and user.
+func AttendeeKey(a *model.Attendee) Key {
+	var occurrence time.Time
+	if a.Occurrence != nil {
+		occurrence = *a.Occurrence
+	}
+	return Key{a.EventID, KeyTime(occurrence), a.UserID}
+}
+
+// Invites are listed by section.
+func InviteKey(i *model.Invite) Key { return Key{i.SectionID, i.ID} }
+
+// Create methods assign a new ID to the entity they are given. Update
+// methods overwrite every mutable column of the entity with the same ID.
+// Get, Update and Delete return ErrNotFound for unknown IDs. GetMany
+// returns the entities with any of the given IDs in no particular order,
+// leaving out unknown IDs. Lists come in the order of their keys, see
+// Page.
+
+type UserRepository interface {
+	Get(ctx context.Context, id string) (*model.User, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByCalendarTokenHash(ctx context.Context, hash string) (*model.User, error)
+	Create(ctx context.Context, u *model.User) error
+	Update(ctx context.Context, u *model.User) error
+	Delete(ctx context.Context, id string) error
+}
+
+type OrganizationRepository interface {
+	Get(ctx context.Context, id string) (*model.Organization, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Organization, error)
+	Create(ctx context.Context, o *model.Organization) error
+	Update(ctx context.Context, o *model.Organization) error
+	// Delete removes the organization together with its sections and
+	// events.
+	Delete(ctx context.Context, id string) error
+}
+
+type SectionRepository interface {
+	Get(ctx context.Context, id string) (*model.Section, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Section, error)
+	// ListByOrganization returns the organization's sections within p, or
+	// all of them if p is nil.
+	ListByOrganization(ctx context.Context, organizationID string, p *Page) ([]*model.Section, error)
+	Create(ctx context.Context, s *model.Section) error
+	Update(ctx context.Context, s *model.Section) error
+	// Delete removes the section together with its members and invites.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemberFilter restricts List to members matching all non-nil fields.
+// SectionIDs selects the members of any of the given sections, Page the
+// members within a page of the list.
+type MemberFilter struct {
+	SectionID  *string
+	SectionIDs []string
+	UserID     *string
+	Right      *int
+	Page       *Page
+}
+
+type MemberRepository interface {
+	Get(ctx context.Context, id string) (*model.Member, error)
+	GetBySectionUser(ctx context.Context, sectionID, userID string) (*model.Member, error)
+	List(ctx context.Context, f MemberFilter) ([]*model.Member, error)
+	Create(ctx context.Context, m *model.Member) error
+	Update(ctx context.Context, m *model.Member) error
+	Delete(ctx context.Context, id string) error
+}
+
+// EventFilter restricts List to events matching all non-nil fields.
+// SeriesIDs selects the events replacing occurrences of any of the given
+// recurring events, Recurring the recurring or the other events. Start
+// and End select events beginning within the given bounds. As recurring
+// events may have occurrences within the bounds however early they begin,
+// Start does not apply to them. Page selects the events within a page of
+// the list.
+type EventFilter struct {
+	OrganizationID *string
+	SeriesID       *string
+	SeriesIDs      []string
+	UID            *string
+	Recurring      *bool
+	Start          *time.Time
+	End            *time.Time
+	Page           *Page
+}
+
+// Events are returned with the sections they are for. Create and Update
+// replace those with the SectionIDs of the given event. Occurrences of
+// recurring events are not stored, only the events replacing some.
+type EventRepository interface {
+	Get(ctx context.Context, id string) (*model.Event, error)
+	GetMany(ctx context.Context, ids []string) ([]*model.Event, error)
+	List(ctx context.Context, f EventFilter) ([]*model.Event, error)
+	// Count returns the number of events List returns for f without a
+	// page.
+	Count(ctx context.Context, f EventFilter) (int, error)
+	Create(ctx context.Context, e *model.Event) error
+	Update(ctx context.Context, e *model.Event) error
+	// Delete removes the event together with its comments, attendees and
+	// the events replacing its occurrences.
+	Delete(ctx context.Context, id string) error
+}
+
+type CommentRepository interface {
+	Get(ctx context.Context, id string) (*model.Comment, error)
+	// ListByEvent returns the event's comments within p, or all of them
+	// if p is nil.
+	ListByEvent(ctx context.Context, eventID string, p *Page) ([]*model.Comment, error)
+	CountByEvent(ctx context.Context, eventID string) (int, error)
+	Create(ctx context.Context, c *model.Comment) error
+	Update(ctx context.Context, c *model.Comment) error
+	Delete(ctx context.Context, id string) error
+}
+
+// AttendeeFilter restricts List to attendees matching all non-nil fields.
+// EventIDs selects the attendees of any of the given events, Page the
+// attendees within a page of the list.
+type AttendeeFilter struct {
+	EventID    *string
+	EventIDs   []string
+	UserID     *string
+	Occurrence *time.Time
+	Commitment *model.Commitment
+	Page       *Page
+}
+
+// Attendees respond either to a single event or to one occurrence of a
+// recurring event. Update may move an attendee to another event or
+// occurrence.
+type AttendeeRepository interface {
+	Get(ctx context.Context, id string) (*model.Attendee, error)
+	// GetByEventUser returns the user's response to the event, or to its
+	// occurrence starting at occurrence if that is not nil.
+	GetByEventUser(ctx context.Context, eventID, userID string, occurrence *time.Time) (*model.Attendee, error)
+	List(ctx context.Context, f AttendeeFilter) ([]*model.Attendee, error)
+	// Count returns the number of attendees List returns for f without a
+	// page.
+	Count(ctx context.Context, f AttendeeFilter) (int, error)
+	Create(ctx context.Context, a *model.Attendee) error
+	Update(ctx context.Context, a *model.Attendee) error
+	Delete(ctx context.Context, id string) error
+}
+
+// InviteFilter restricts List to invites matching all non-nil fields,
+// Page to the invites within a page of the list.
+type InviteFilter struct {
+	SectionID *string
+	UserID    *string
+	Page      *Page
+}
+
+type InviteRepository interface {
+	Get(ctx context.Context, id string) (*model.Invite, error)
+	GetByTokenHash(ctx context.Context, hash string) (*model.Invite, error)
+	List(ctx context.Context, f InviteFilter) ([]*model.Invite, error)
+	Create(ctx context.Context, i *model.Invite) error
+	Delete(ctx context.Context, id string) error
+}
+
+// RefreshToken is an issued refresh token. Only a hash of the token is
+// kept. Tokens issued by rotating an earlier token share its Family.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	Family    string
+	Hash      string
+	ExpiresAt time.Time
+	Used      bool
+	Revoked   bool
+}
+
+type RefreshTokenRepository interface {
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	Create(ctx context.Context, t *RefreshToken) error
+	// MarkUsed flags an unused token as used. It returns ErrNotFound if
+	// the token does not exist or has been used already.
+	MarkUsed(ctx context.Context, id string) error
+	// RevokeFamily revokes every token of the given family.
+	RevokeFamily(ctx context.Context, family string) error
+	// RevokeUser revokes every token issued to the given user.
+	RevokeUser(ctx context.Context, userID string) error
+}